@@ -4,6 +4,7 @@
 package application
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -90,17 +91,36 @@ type TextWithColour struct {
 // it's data and making requests to the Awtrix device.
 type Application struct {
 	Name           string
-	fetcher        func(*Application, *http.Client) error
+	fetcher        func(context.Context, *Application, *http.Client) error
 	Data           AppData
 	GlobalConfig   awtrix.Config
 	PollRate       time.Duration
 	lastPolled     time.Time
 	PushOnNextCall bool
-	HTTPClient     *http.Client
+	// ForcePushOnNextCall sends the next push even when its payload is unchanged, it is reset before each fetch.
+	ForcePushOnNextCall bool
+	HTTPClient          *http.Client
+	// hidden removes the custom app from the device's loop until the application is shown again.
+	hidden bool
+	// pages replaces Data once paged is set, see SetPages.
+	pages []AppData
+	paged bool
 }
 
 // NewApplication instantiates a new altar application.
 func NewApplication(name string, fetcher func(*Application, *http.Client) error) Application {
+	return NewApplicationWithContext(name, func(_ context.Context, app *Application, client *http.Client) error {
+		return fetcher(app, client)
+	})
+}
+
+// NewApplicationWithContext instantiates a new altar application whose fetcher receives a context from the broker.
+//
+// The context carries the broker's deadline for each fetch and is cancelled when the broker shuts down.
+func NewApplicationWithContext(
+	name string,
+	fetcher func(context.Context, *Application, *http.Client) error,
+) Application {
 	return Application{
 		Name:           name,
 		fetcher:        fetcher,
@@ -116,6 +136,11 @@ func (a *Application) SetPollRateByRateLimit(requests uint32, duration time.Dura
 	a.PollRate = duration / time.Duration(requests)
 }
 
+// Wake makes the application's next fetch run regardless of its poll rate.
+func (a *Application) Wake() {
+	a.lastPolled = time.Time{}
+}
+
 // ShouldFetch defines whether an application should be fetched again according to it's poll rate.
 func (a *Application) ShouldFetch() bool {
 	return time.Since(a.lastPolled) > a.PollRate
@@ -126,6 +151,42 @@ func (a *Application) ShouldPushToAwtrix() bool {
 	return a.PushOnNextCall
 }
 
+// ShouldForcePush defines whether an application's next push should be sent even when its data is unchanged.
+func (a *Application) ShouldForcePush() bool {
+	return a.ForcePushOnNextCall
+}
+
+// Hide removes the application's custom app from the device's loop on the next push. The application keeps being
+// fetched while hidden, and its app is pushed again once Show is called.
+func (a *Application) Hide() {
+	a.hidden = true
+	a.PushOnNextCall = true
+}
+
+// Show returns a hidden application's custom app to the device's loop on the next push.
+func (a *Application) Show() {
+	a.hidden = false
+	a.PushOnNextCall = true
+}
+
+// SetPages replaces the application's data with a list of pages, each shown as its own app in the device's loop
+// named with the page's index appended to the application's name. Once pages are set Data is no longer pushed. The
+// broker removes pages that disappear between fetches, so an empty list removes every page.
+func (a *Application) SetPages(pages []AppData) {
+	a.pages = pages
+	a.paged = true
+}
+
+// PageCount returns the number of pages the application has, and false when it has not been given pages.
+func (a *Application) PageCount() (int, bool) {
+	return len(a.pages), a.paged
+}
+
+// ShouldRemove defines whether the application's custom app should be removed from the device.
+func (a *Application) ShouldRemove() bool {
+	return a.hidden
+}
+
 // GetName returns the name of the application.
 func (a *Application) GetName() string {
 	return a.Name
@@ -137,7 +198,7 @@ func (a *Application) GetPollRate() time.Duration {
 }
 
 // Fetch uses the application's fetcher to query for new data.
-func (a *Application) Fetch(client *http.Client) error {
+func (a *Application) Fetch(ctx context.Context, client *http.Client) error {
 	if !a.ShouldFetch() {
 		slog.Debug("skipping app fetch", "app", a.Name,
 			"seconds-since-last-fetch", time.Since(a.lastPolled).Seconds(), "poll-rate-seconds", a.PollRate.Seconds())
@@ -149,16 +210,26 @@ func (a *Application) Fetch(client *http.Client) error {
 		"seconds-since-last-fetch", time.Since(a.lastPolled).Seconds(), "poll-rate-seconds", a.PollRate.Seconds())
 
 	a.lastPolled = time.Now()
+	a.ForcePushOnNextCall = false
 	a.PushOnNextCall = true
 
-	return a.fetcher(a, client)
+	return a.fetcher(ctx, a, client)
 }
 
 // GetData returns the application's current data.
 func (a *Application) GetData() any {
+	if a.paged {
+		return a.pages
+	}
+
 	return a.Data
 }
 
+// Endpoint returns the Awtrix endpoint of the application's custom app.
+func (a *Application) Endpoint() (awtrix.Endpoint, error) {
+	return awtrix.CustomAppEndpoint(a.Name), nil
+}
+
 // GetGlobalConfig returns the Awtrix configuration this application has requested to change.
 func (a *Application) GetGlobalConfig() awtrix.Config {
 	return a.GlobalConfig
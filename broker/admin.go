@@ -0,0 +1,87 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// routineHandler performs a command acting on the routine named name. It responds with 404 when the broker has no
+// such routine, and 409 when a paused routine is asked to fetch.
+func (b *HTTPBroker) routineHandler(wrtr http.ResponseWriter, command AltarAdminCommand, name string) {
+	var err error
+
+	switch command {
+	case AdminInspectCommand:
+		var status RoutineStatus
+
+		status, err = b.Routine(name)
+		if err == nil {
+			writeJSON(wrtr, status)
+
+			return
+		}
+	case AdminFetchCommand:
+		err = b.Wake(name)
+	case AdminPushCommand:
+		err = b.Push(name)
+	case AdminPauseCommand:
+		err = b.Pause(name)
+	case AdminResumeCommand:
+		err = b.Resume(name)
+	}
+
+	switch {
+	case errors.Is(err, ErrRoutineNotFound):
+		wrtr.WriteHeader(http.StatusNotFound)
+		_, _ = wrtr.Write([]byte("admin server did not recognise the routine: '" + name + "'"))
+	case errors.Is(err, ErrRoutinePaused):
+		wrtr.WriteHeader(http.StatusConflict)
+		_, _ = wrtr.Write([]byte(err.Error()))
+	default:
+		slog.Info("admin server performed routine command", "command", command, "routine", name)
+		wrtr.WriteHeader(http.StatusOK)
+	}
+}
+
+// setConfigHandler replaces the broker's display configuration with cfg, responding with 502 when it could not be
+// applied to every device.
+func (b *HTTPBroker) setConfigHandler(ctx context.Context, wrtr http.ResponseWriter, cfg *awtrix.Config) {
+	if cfg == nil {
+		wrtr.WriteHeader(http.StatusBadRequest)
+		_, _ = wrtr.Write([]byte("set config command did not include a config"))
+
+		return
+	}
+
+	err := b.SetDisplayConfig(ctx, *cfg)
+	if err != nil {
+		slog.Error("admin server failed to apply display config", "error", err)
+		wrtr.WriteHeader(http.StatusBadGateway)
+		_, _ = wrtr.Write([]byte(err.Error()))
+
+		return
+	}
+
+	slog.Info("admin server set display config")
+	wrtr.WriteHeader(http.StatusOK)
+}
+
+// writeJSON responds with value encoded as json.
+func writeJSON(wrtr http.ResponseWriter, value any) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		slog.Error("admin server failed to marshal response", "error", err)
+		wrtr.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	wrtr.Header().Set("Content-Type", "application/json")
+	wrtr.WriteHeader(http.StatusOK)
+	_, _ = wrtr.Write(body)
+}
@@ -0,0 +1,93 @@
+package broker
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// AuthPolicy authorises a request to the admin server, returning an error when the request should be rejected. A nil
+// policy allows every request.
+type AuthPolicy func(*http.Request) error
+
+// ErrUnauthorised occurs when a request to the admin server does not satisfy its auth policy.
+var ErrUnauthorised = errors.New("request is not authorised")
+
+// DefaultSignatureHeader is the header HMACSignature reads a request's signature from when it is not given a header.
+const DefaultSignatureHeader = "X-Hub-Signature-256"
+
+// maxSignedBodyBytes bounds the size of a request body HMACSignature reads to verify its signature.
+const maxSignedBodyBytes = 1 << 20
+
+// BearerToken returns a policy allowing requests whose Authorization header carries token as a bearer token. An empty
+// token rejects every request.
+func BearerToken(token string) AuthPolicy {
+	return func(req *http.Request) error {
+		provided, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return fmt.Errorf("%w: missing or incorrect bearer token", ErrUnauthorised)
+		}
+
+		return nil
+	}
+}
+
+// HMACSignature returns a policy allowing requests whose header carries the hex encoded HMAC-SHA256 of the request's
+// body keyed with secret, optionally prefixed with "sha256=" as GitHub signs its webhooks. The body is left intact for
+// the handler to read. An empty secret rejects every request.
+func HMACSignature(secret string, header string) AuthPolicy {
+	if header == "" {
+		header = DefaultSignatureHeader
+	}
+
+	return func(req *http.Request) error {
+		signature, err := hex.DecodeString(strings.TrimPrefix(req.Header.Get(header), "sha256="))
+		if err != nil || len(signature) == 0 || secret == "" {
+			return fmt.Errorf("%w: missing or malformed signature in %v", ErrUnauthorised, header)
+		}
+
+		body, err := io.ReadAll(io.LimitReader(req.Body, maxSignedBodyBytes))
+		if err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		_, _ = mac.Write(body)
+
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return fmt.Errorf("%w: incorrect signature in %v", ErrUnauthorised, header)
+		}
+
+		return nil
+	}
+}
+
+// authorised wraps handler, responding with 401 to requests policy rejects.
+func authorised(policy AuthPolicy, handler http.HandlerFunc) http.HandlerFunc {
+	if policy == nil {
+		return handler
+	}
+
+	return func(wrtr http.ResponseWriter, req *http.Request) {
+		err := policy(req)
+		if err != nil {
+			slog.Warn("admin server rejected request", "path", req.URL.Path, "error", err)
+			http.Error(wrtr, "request is not authorised", http.StatusUnauthorized)
+
+			return
+		}
+
+		handler(wrtr, req)
+	}
+}
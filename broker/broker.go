@@ -4,7 +4,6 @@
 package broker
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -12,22 +11,26 @@ import (
 	"io"
 	"log"
 	"log/slog"
+	"maps"
 	"net"
 	"net/http"
-	"net/url"
-	"os"
+	"slices"
 	"sync"
 	"time"
 
-	"github.com/t-monaghan/altar/application"
-	"github.com/t-monaghan/altar/notifier"
 	"github.com/t-monaghan/altar/utils"
 	"github.com/t-monaghan/altar/utils/awtrix"
 )
 
 const httpTimeout = 10 * time.Second
 const idleTimeout = 120 * time.Second
-const mockAwtrixPort = ":8080"
+const mockAwtrixPort = "8080"
+
+// DefaultFetchTimeout is the default deadline given to each routine's fetch.
+const DefaultFetchTimeout = 30 * time.Second
+
+// DefaultShutdownTimeout is the default duration the broker waits for in-flight work to finish when shutting down.
+const DefaultShutdownTimeout = 15 * time.Second
 
 // DefaultAdminPort is the default port for the broker's api.
 const DefaultAdminPort = "25827"
@@ -36,6 +39,8 @@ const DefaultAdminPort = "25827"
 type AltarAdminRequest struct {
 	Command AltarAdminCommand `json:"command"`
 	Data    string            `json:"data,omitempty"`
+	// Config is the display configuration set by the set config command.
+	Config *awtrix.Config `json:"config,omitempty"`
 }
 
 // AltarAdminCommand defines the commands the altar admin server recognises.
@@ -44,41 +49,124 @@ type AltarAdminCommand string
 const (
 	// AdminShutdownCommand is the command recognised by altar's admin server as a call to shutdown.
 	AdminShutdownCommand AltarAdminCommand = "DOWN"
+	// AdminDismissCommand is the command recognised by altar's admin server as a call to dismiss the notification
+	// being shown. Its data names the device to dismiss the notification on, every device when it is empty.
+	AdminDismissCommand AltarAdminCommand = "DISMISS"
+	// AdminListCommand is the command recognised by altar's admin server as a call to list the status of every
+	// routine.
+	AdminListCommand AltarAdminCommand = "LIST"
+	// AdminInspectCommand is the command recognised by altar's admin server as a call to report the status of the
+	// routine named by its data.
+	AdminInspectCommand AltarAdminCommand = "INSPECT"
+	// AdminFetchCommand is the command recognised by altar's admin server as a call to fetch and push the routine
+	// named by its data without waiting for its next poll.
+	AdminFetchCommand AltarAdminCommand = "FETCH"
+	// AdminPushCommand is the command recognised by altar's admin server as a call to push the last data of the
+	// routine named by its data, even when it is unchanged.
+	AdminPushCommand AltarAdminCommand = "PUSH"
+	// AdminPauseCommand is the command recognised by altar's admin server as a call to pause the routine named by its
+	// data.
+	AdminPauseCommand AltarAdminCommand = "PAUSE"
+	// AdminResumeCommand is the command recognised by altar's admin server as a call to resume the routine named by
+	// its data.
+	AdminResumeCommand AltarAdminCommand = "RESUME"
+	// AdminGetConfigCommand is the command recognised by altar's admin server as a call to report the broker's display
+	// configuration.
+	AdminGetConfigCommand AltarAdminCommand = "GET_CONFIG"
+	// AdminSetConfigCommand is the command recognised by altar's admin server as a call to replace the broker's display
+	// configuration with its config and apply it to every device.
+	AdminSetConfigCommand AltarAdminCommand = "SET_CONFIG"
 )
 
 // HTTPBroker performs each routine's fetching, hosts handler functions on it's server and communicates updates to
 // the Awtrix host.
 type HTTPBroker struct {
-	routines      []utils.Routine
-	clockAddress  string
-	Client        *http.Client
-	DebugMode     bool
-	MockAwtrix    bool
+	routines   []utils.Routine
+	devices    []*device
+	Client     *http.Client
+	DebugMode  bool
+	MockAwtrix bool
+	// DisplayConfig is the broker's base configuration for every Awtrix device, each device's options and the
+	// configuration requested by routines take precedence over it. Once the broker is running it must be read and
+	// changed with GetDisplayConfig and SetDisplayConfig.
 	DisplayConfig awtrix.Config
 	AdminPort     string
-	handlers      map[string]func(http.ResponseWriter, *http.Request)
+	// AdminAddress is the host the admin server listens on, such as "127.0.0.1", every interface when it is empty.
+	AdminAddress string
+	// AdminListener is the listener the admin server serves on, such as one bound to an ephemeral port. When it is
+	// nil the admin server listens on AdminAddress and AdminPort. The listener is closed once the admin server shuts down.
+	AdminListener net.Listener
+	// AdminAuth authorises requests to the admin server's commands, which are unauthenticated when it is nil.
+	AdminAuth AuthPolicy
+	// HandlerAuth authorises requests to the handler hosted on each path, handlers without a policy are
+	// unauthenticated. Each path must be a path the broker hosts a handler on.
+	HandlerAuth map[string]AuthPolicy
+	// TLSCertFile and TLSKeyFile are the paths of a certificate and its private key, when both are set the admin
+	// server only accepts https requests.
+	TLSCertFile string
+	TLSKeyFile  string
+	// DropCounters are the queues behind the broker's handlers, keyed by the handler's path, whose dropped messages are
+	// reported by the admin server's metrics.
+	DropCounters map[string]DropCounter
+	// RetryPolicy controls how every request to the Awtrix device is retried.
+	RetryPolicy awtrix.RetryPolicy
+	// FetchTimeout is the deadline given to each fetch, the fetch's context is cancelled once it passes.
+	FetchTimeout time.Duration
+	// ShutdownTimeout bounds how long Run waits for the admin server and in-flight routines to stop.
+	ShutdownTimeout time.Duration
+	// HealthCheckInterval is the interval between checks of each Awtrix device's stats, used to re-provision a
+	// device after it reboots or returns from being offline. A negative interval disables health checks, which Run
+	// rejects when a device has a resolver, as devices are only re-resolved once a health check finds them offline.
+	HealthCheckInterval time.Duration
+	// StateFile is the path of a file the broker records the custom apps it owns on each device, and their pages, in.
+	// Cleaning up stale apps requires a StateFile, when set the broker removes the apps and pages it owned when it last
+	// ran but no longer has a routine for each time it starts. Without one, apps left behind by renamed or removed
+	// routines stay on the device and the broker logs a warning when it starts.
+	StateFile string
+	scheduled []*scheduledRoutine
+	handlers  map[string]func(http.ResponseWriter, *http.Request)
+	metrics   *metrics
+	// stateMu serialises writes to the StateFile and guards strandedApps, the stale apps the broker failed to remove
+	// from each device and the number of pages each may have, keyed by the device's name then the app's name.
+	stateMu      sync.Mutex
+	strandedApps map[string]map[string]int
+	// requestsMu guards the configuration requested by each scheduled routine and the broker's DisplayConfig.
+	requestsMu sync.Mutex
+	stopMu     sync.Mutex
+	stop       context.CancelFunc
 }
 
 // ErrBrokerHasNoApplications occurs when an altar Broker is instantiated with no applications.
 var ErrBrokerHasNoApplications = errors.New("failed to initialise broker: no applications were provided")
 
-// ErrIPNotValid occurs when an invalid IP address is provided.
-var ErrIPNotValid = errors.New("IP address is not valid")
+// ErrShutdownTimedOut occurs when the broker's routines do not stop within the broker's shutdown timeout.
+var ErrShutdownTimedOut = errors.New("timed out waiting for broker routines to stop")
 
-// NewBroker instantiates a new altar broker.
+// NewBroker instantiates a new altar broker for a single Awtrix device.
 func NewBroker(
 	awtrixAddress string,
 	routines []utils.Routine,
 	handlers map[string]func(http.ResponseWriter, *http.Request),
 	options ...func(*awtrix.Config),
+) (*HTTPBroker, error) {
+	return NewMultiDeviceBroker([]Device{{Address: awtrixAddress}}, routines, handlers, options...)
+}
+
+// NewMultiDeviceBroker instantiates a new altar broker for several Awtrix devices. Each routine is fetched once per
+// poll and its data is pushed to every device that displays it. The options configure every device, each device's
+// own options take precedence over them.
+func NewMultiDeviceBroker(
+	devices []Device,
+	routines []utils.Routine,
+	handlers map[string]func(http.ResponseWriter, *http.Request),
+	options ...func(*awtrix.Config),
 ) (*HTTPBroker, error) {
 	if len(routines) == 0 {
 		return nil, ErrBrokerHasNoApplications
 	}
 
-	clockIP := net.ParseIP(awtrixAddress)
-	if clockIP == nil {
-		return nil, ErrIPNotValid
+	if len(devices) == 0 {
+		return nil, ErrBrokerHasNoDevices
 	}
 
 	cfg := awtrix.Config{}
@@ -86,238 +174,329 @@ func NewBroker(
 		option(&cfg)
 	}
 
-	brkr := HTTPBroker{
-		clockAddress:  fmt.Sprintf("http://%v", clockIP),
-		routines:      routines,
-		Client:        &http.Client{Timeout: httpTimeout},
-		DebugMode:     false,
-		DisplayConfig: cfg,
-		handlers:      handlers,
+	scheduled := make([]*scheduledRoutine, len(routines))
+	routineNames := make(map[string]bool, len(routines))
+
+	for i, routine := range routines {
+		scheduled[i] = newScheduledRoutine(routine)
+		routineNames[routine.GetName()] = true
 	}
 
-	return &brkr, nil
-}
+	metrics := newMetrics(slices.Collect(maps.Keys(routineNames)))
 
-// Start begins execution of the broker's routine.
-func (b *HTTPBroker) Start() {
-	if b.DebugMode {
-		slog.SetLogLoggerLevel(slog.LevelDebug)
-	} else {
-		// avoid rebooting when debugging
-		err := b.sendConfig()
+	managed := make([]*device, len(devices))
+	deviceNames := make(map[string]bool, len(devices))
+
+	for i, spec := range devices {
+		dvc, err := newDevice(spec, routineNames)
 		if err != nil {
-			slog.Error("error setting up initial awtrix configuration", "error", err)
+			return nil, err
 		}
 
-		slog.Info("rebooting awtrix device")
-
-		// rebooting awtrix is required to ensure the configuration is applied
-		err = b.rebootAwtrix()
-		if err != nil {
-			slog.Error("error rebooting the awtrix device", "error", err)
+		if deviceNames[dvc.name] {
+			return nil, fmt.Errorf("%w: %v", ErrDuplicateDeviceName, dvc.name)
 		}
-	}
 
-	go func() {
-		fetchAndPushApps(b)
-	}()
+		deviceNames[dvc.name] = true
+		managed[i] = dvc
+	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/admin/command", commandHandler)
+	brkr := HTTPBroker{
+		routines:        routines,
+		devices:         managed,
+		Client:          &http.Client{Timeout: httpTimeout},
+		DebugMode:       false,
+		DisplayConfig:   cfg,
+		RetryPolicy:     awtrix.DefaultRetryPolicy(),
+		FetchTimeout:    DefaultFetchTimeout,
+		ShutdownTimeout: DefaultShutdownTimeout,
+		scheduled:       scheduled,
+		handlers:        handlers,
+		metrics:         metrics,
+	}
+
+	for _, dvc := range brkr.devices {
+		if dvc.client == nil {
+			dvc.client = &httpDeviceClient{broker: &brkr, device: dvc}
+		}
 
-	for path, handler := range b.handlers {
-		mux.HandleFunc(path, handler)
+		dvc.client = &meteredClient{Client: dvc.client, device: dvc.name, metrics: metrics}
 	}
 
-	adminPort := DefaultAdminPort
+	return &brkr, nil
+}
 
-	if b.AdminPort != "" {
-		adminPort = b.AdminPort
+// Start begins execution of the broker's routine, blocking until the broker is shut down.
+//
+// Start exits the process if the broker fails, use Run to handle the error instead.
+func (b *HTTPBroker) Start() {
+	err := b.Run(context.Background())
+	if err != nil {
+		log.Fatal(err)
 	}
+}
 
-	adminServer := &http.Server{
-		Addr:         ":" + adminPort,
-		Handler:      mux,
-		ReadTimeout:  httpTimeout,
-		WriteTimeout: httpTimeout,
-		IdleTimeout:  idleTimeout,
+// Run executes the broker until ctx is cancelled or the admin server receives a shutdown command.
+//
+// When shutting down, in-flight fetches are cancelled, pending pushes are sent to the Awtrix device and the admin
+// server is gracefully shut down before Run returns.
+func (b *HTTPBroker) Run(ctx context.Context) error {
+	err := b.validateAdminConfig()
+	if err != nil {
+		return err
 	}
 
-	log.Fatal(adminServer.ListenAndServe())
-}
+	err = b.validateHealthConfig()
+	if err != nil {
+		return err
+	}
 
-func fetchAndPushApps(brkr *HTTPBroker) {
-	for {
-		startTime := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-		var quickestPoll = time.Hour * 9000
+	b.stopMu.Lock()
+	b.stop = cancel
+	b.stopMu.Unlock()
 
-		var fetchGroup sync.WaitGroup
+	b.resolveDevices(ctx)
 
-		var mutateConfigAndSetPollRate sync.Mutex
+	if b.DebugMode {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	} else {
+		// avoid rebooting when debugging
+		b.provisionDevices(ctx)
+	}
 
-		for _, app := range brkr.routines {
-			fetchGroup.Add(1)
+	b.removeStaleApps(ctx)
 
-			go func(app utils.Routine) {
-				defer fetchGroup.Done()
+	routinesDone := make(chan struct{})
 
-				defer func() {
-					if r := recover(); r != nil {
-						slog.Error("broker has recovered from fetcher panicking", "error", r)
-					}
-				}()
+	go func() {
+		defer close(routinesDone)
+
+		var routineGroup sync.WaitGroup
+
+		routineGroup.Add(1 + len(b.devices))
 
-				err := app.Fetch(brkr.Client)
-				if err != nil {
-					slog.Error("error encountered in fetching", "app", app.GetName(), "error", err)
-				}
+		go func() {
+			defer routineGroup.Done()
 
-				mutateConfigAndSetPollRate.Lock()
-				brkr.DisplayConfig = mergeConfig(brkr.DisplayConfig, app.GetGlobalConfig())
+			b.runRoutines(ctx)
+		}()
 
-				if app.GetPollRate() < quickestPoll {
-					quickestPoll = app.GetPollRate()
-				}
-				mutateConfigAndSetPollRate.Unlock()
-			}(app)
+		for _, dvc := range b.devices {
+			go func() {
+				defer routineGroup.Done()
+
+				b.monitorHealth(ctx, dvc)
+			}()
 		}
 
-		fetchGroup.Wait()
+		routineGroup.Wait()
+	}()
 
-		err := brkr.sendConfig()
+	adminServer := b.adminServer()
+	serverErr := make(chan error, 1)
+
+	go func() {
+		err := b.serveAdmin(adminServer)
 		if err != nil {
-			slog.Error("error changing awtrix settings", "error", err)
+			serverErr <- err
 		}
 
-		for _, app := range brkr.routines {
-			err := brkr.push(app)
-			if err != nil {
-				slog.Error("error encountered pushing to awtrix device", "app", app.GetName(), "error", err)
-			}
-		}
+		close(serverErr)
+	}()
 
-		duration := time.Since(startTime)
-		if duration < quickestPoll {
-			time.Sleep(quickestPoll - duration)
-		}
+	var runErr error
+
+	select {
+	case <-ctx.Done():
+	case runErr = <-serverErr:
 	}
+
+	cancel()
+
+	slog.Info("shutting down broker")
+
+	return errors.Join(runErr, b.shutdown(ctx, adminServer, routinesDone))
 }
 
-func (b *HTTPBroker) sendConfig() error {
-	jsonData, err := json.Marshal(b.DisplayConfig)
-	if err != nil {
-		return fmt.Errorf("failed to marshal awtrix config into json: %w", err)
+// serveAdmin serves the admin server on the broker's AdminListener, or on its configured address when it has none,
+// until the server is shut down.
+func (b *HTTPBroker) serveAdmin(adminServer *http.Server) error {
+	var err error
+
+	switch {
+	case b.AdminListener != nil && b.TLSCertFile != "":
+		err = adminServer.ServeTLS(b.AdminListener, b.TLSCertFile, b.TLSKeyFile)
+	case b.AdminListener != nil:
+		err = adminServer.Serve(b.AdminListener)
+	case b.TLSCertFile != "":
+		err = adminServer.ListenAndServeTLS(b.TLSCertFile, b.TLSKeyFile)
+	default:
+		err = adminServer.ListenAndServe()
 	}
 
-	bufferedJSON := bytes.NewBuffer(jsonData)
-
-	port := ""
-	if b.MockAwtrix {
-		port = mockAwtrixPort
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("admin server failed: %w", err)
 	}
 
-	address := fmt.Sprintf("%v%v/api/settings", b.clockAddress, port)
+	return nil
+}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, address, bufferedJSON)
-	if err != nil {
-		return fmt.Errorf("failed to create post request for awtrix configuration: %w", err)
+// resolveDevices looks up the address of every device with a resolver.
+func (b *HTTPBroker) resolveDevices(ctx context.Context) {
+	var resolving sync.WaitGroup
+
+	for _, dvc := range b.devices {
+		resolving.Add(1)
+
+		go func() {
+			defer resolving.Done()
+
+			_, err := dvc.resolveAddress(ctx)
+			if err != nil {
+				slog.Error("error resolving the awtrix device", "device", dvc.name, "error", err)
+			}
+		}()
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	resolving.Wait()
+}
 
-	resp, err := b.Client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to perform post request for awtrix configuration: %w", err)
+// provisionDevices applies the initial configuration to every device and reboots them.
+func (b *HTTPBroker) provisionDevices(ctx context.Context) {
+	var provisioning sync.WaitGroup
+
+	for _, dvc := range b.devices {
+		provisioning.Add(1)
+
+		go func() {
+			defer provisioning.Done()
+
+			err := b.applyConfig(ctx, dvc, true)
+			if err != nil {
+				slog.Error("error setting up initial awtrix configuration", "device", dvc.name, "error", err)
+			}
+
+			slog.Info("rebooting awtrix device", "device", dvc.name)
+
+			// rebooting awtrix is required to ensure the configuration is applied
+			err = b.rebootAwtrix(ctx, dvc)
+			if err != nil {
+				slog.Error("error rebooting the awtrix device", "device", dvc.name, "error", err)
+			}
+		}()
 	}
 
-	defer func() {
-		closeErr := resp.Body.Close()
-		if err == nil {
-			err = closeErr
-		}
-	}()
+	provisioning.Wait()
+}
 
-	if utils.ResponseStatusIsNot2xx(resp.StatusCode) {
-		slog.Error("awtrix has responded to configuration request with non-2xx http response", "http-status", resp.Status)
+// shutdownTimeout returns the broker's ShutdownTimeout, or DefaultShutdownTimeout when it is not set.
+func (b *HTTPBroker) shutdownTimeout() time.Duration {
+	if b.ShutdownTimeout <= 0 {
+		return DefaultShutdownTimeout
 	}
 
-	return err
+	return b.ShutdownTimeout
 }
 
-// ErrUnknownRoutineType is thrown when altar encounters a concrete routine type that it does not recognise.
-// Strictly only the types defined by altar are used as the broker's push method needs to know how to handle the
-// request.
-var ErrUnknownRoutineType = errors.New("unknown routine type")
+// shutdown gracefully stops the admin server and waits for the broker's routines and health monitor to finish.
+func (b *HTTPBroker) shutdown(ctx context.Context, adminServer *http.Server, routinesDone <-chan struct{}) error {
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.shutdownTimeout())
+	defer cancel()
 
-func (b *HTTPBroker) push(routine utils.Routine) error {
-	if !routine.ShouldPushToAwtrix() {
-		slog.Debug("skipping push for routine", "routine", routine.GetName())
+	var shutdownErr error
 
-		return nil
+	err := adminServer.Shutdown(shutdownCtx)
+	if err != nil {
+		shutdownErr = fmt.Errorf("failed to shut down admin server: %w", err)
 	}
 
-	jsonData, err := json.Marshal(routine.GetData())
-	if err != nil {
-		return fmt.Errorf("failed to marshal %v data into json: %w", routine.GetName(), err)
+	select {
+	case <-routinesDone:
+	case <-shutdownCtx.Done():
+		shutdownErr = errors.Join(shutdownErr, ErrShutdownTimedOut)
 	}
 
-	bufferedJSON := bytes.NewBuffer(jsonData)
+	return shutdownErr
+}
 
-	port := ""
-	if b.MockAwtrix {
-		port = mockAwtrixPort
-	}
+// Shutdown signals a running broker to stop, it does not wait for the broker to finish.
+func (b *HTTPBroker) Shutdown() {
+	b.stopMu.Lock()
+	defer b.stopMu.Unlock()
 
-	var address string
-	switch routine.(type) {
-	case *application.Application:
-		address = fmt.Sprintf("%v%v/api/custom?name=%v", b.clockAddress, port, url.QueryEscape(routine.GetName()))
-	case *notifier.Notifier:
-		address = fmt.Sprintf("%v%v/api/notify", b.clockAddress, port)
-	default:
-		return fmt.Errorf("%w for routine: %v", ErrUnknownRoutineType, routine.GetName())
+	if b.stop != nil {
+		b.stop()
 	}
+}
 
-	err = b.postRequestToAwtrix(address, bufferedJSON, routine.GetName())
-	if err != nil {
-		return err
+// ErrInvalidAdminConfig occurs when the broker's admin server is configured incorrectly.
+var ErrInvalidAdminConfig = errors.New("invalid admin server configuration")
+
+// validateAdminConfig checks the admin server's configuration before the broker starts, so a mistake cannot leave a
+// handler unprotected.
+func (b *HTTPBroker) validateAdminConfig() error {
+	if (b.TLSCertFile == "") != (b.TLSKeyFile == "") {
+		return fmt.Errorf("%w: both a TLS certificate and key must be provided", ErrInvalidAdminConfig)
 	}
 
-	slog.Debug("pushed", "routine-name", routine.GetName())
+	for path := range b.HandlerAuth {
+		if _, ok := b.handlers[path]; !ok {
+			return fmt.Errorf("%w: auth policy given for %v, which has no handler", ErrInvalidAdminConfig, path)
+		}
+	}
 
-	return err
+	return nil
 }
 
-func (b *HTTPBroker) postRequestToAwtrix(address string, bufferedJSON *bytes.Buffer, routineName string) error {
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, address, bufferedJSON)
-	if err != nil {
-		return fmt.Errorf("failed to create post request for %v: %w", routineName, err)
+func (b *HTTPBroker) adminServer() *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/command", authorised(b.AdminAuth, b.commandHandler))
+	mux.HandleFunc("/metrics", authorised(b.AdminAuth, b.metricsHandler))
+
+	for path, handler := range b.handlers {
+		mux.HandleFunc(path, authorised(b.HandlerAuth[path], handler))
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	adminPort := DefaultAdminPort
 
-	resp, err := b.Client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to perform post request for %v: %w", routineName, err)
+	if b.AdminPort != "" {
+		adminPort = b.AdminPort
 	}
 
-	defer func() {
-		closeErr := resp.Body.Close()
-		if err == nil && closeErr != nil {
-			err = fmt.Errorf("%w for routine %v: %w", utils.ErrClosingResponseBody, routineName, closeErr)
-		}
-	}()
+	if b.AdminAuth == nil && b.AdminAddress == "" {
+		slog.Warn("admin commands are unauthenticated and served on every interface, set AdminAuth or AdminAddress")
+	}
 
-	if utils.ResponseStatusIsNot2xx(resp.StatusCode) {
-		slog.Error("awtrix has responded to push with non-2xx http response",
-			"http-status", resp.Status, "routine", routineName)
+	return &http.Server{
+		Addr:         net.JoinHostPort(b.AdminAddress, adminPort),
+		Handler:      mux,
+		ReadTimeout:  httpTimeout,
+		WriteTimeout: httpTimeout,
+		IdleTimeout:  idleTimeout,
 	}
+}
+
+// ErrUnknownRoutineType is thrown when altar encounters a routine that does not declare the Awtrix endpoint it pushes
+// to, routines must implement utils.EndpointProvider for the broker to know how to handle the request.
+var ErrUnknownRoutineType = errors.New("unknown routine type")
 
-	return err
+// withRetries calls request, a request to endpoint, with the device's client, retrying according to the broker's retry
+// policy.
+func (b *HTTPBroker) withRetries(
+	ctx context.Context,
+	dvc *device,
+	endpoint awtrix.Endpoint,
+	request func(context.Context, awtrix.Client) error,
+) error {
+	return b.RetryPolicy.Do(ctx, endpoint, func(ctx context.Context) error {
+		return request(ctx, dvc.client)
+	})
 }
 
-func commandHandler(wrtr http.ResponseWriter, req *http.Request) {
+func (b *HTTPBroker) commandHandler(wrtr http.ResponseWriter, req *http.Request) {
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
 		http.Error(wrtr, "Error reading request body", http.StatusInternalServerError)
@@ -350,7 +529,18 @@ func commandHandler(wrtr http.ResponseWriter, req *http.Request) {
 	switch requestCommand.Command {
 	case AdminShutdownCommand:
 		slog.Info("admin server received shutdown command - shutting down")
-		os.Exit(0)
+		wrtr.WriteHeader(http.StatusOK)
+		b.Shutdown()
+	case AdminDismissCommand:
+		b.dismissHandler(req.Context(), wrtr, requestCommand.Data)
+	case AdminListCommand:
+		writeJSON(wrtr, b.Routines())
+	case AdminInspectCommand, AdminFetchCommand, AdminPushCommand, AdminPauseCommand, AdminResumeCommand:
+		b.routineHandler(wrtr, requestCommand.Command, requestCommand.Data)
+	case AdminGetConfigCommand:
+		writeJSON(wrtr, b.GetDisplayConfig())
+	case AdminSetConfigCommand:
+		b.setConfigHandler(req.Context(), wrtr, requestCommand.Config)
 	default:
 		wrtr.WriteHeader(http.StatusBadRequest)
 		_, _ = wrtr.Write([]byte("admin server did not recognise the command: '" + string(body) + "'"))
@@ -359,46 +549,53 @@ func commandHandler(wrtr http.ResponseWriter, req *http.Request) {
 	}
 }
 
-func (b *HTTPBroker) rebootAwtrix() error {
-	port := ""
-	if b.MockAwtrix {
-		port = mockAwtrixPort
-	}
+// dismissHandler dismisses the notification shown on the device named deviceName, or on every device when deviceName
+// is empty.
+func (b *HTTPBroker) dismissHandler(ctx context.Context, wrtr http.ResponseWriter, deviceName string) {
+	devices := b.devices
 
-	address := fmt.Sprintf("%v%v/api/reboot", b.clockAddress, port)
+	if deviceName != "" {
+		devices = nil
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, address, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create post request for rebooting awtrix device: %w", err)
-	}
+		for _, dvc := range b.devices {
+			if dvc.name == deviceName {
+				devices = []*device{dvc}
+			}
+		}
 
-	resp, err := b.Client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to perform post request for rebooting awtrix device: %w", err)
-	}
+		if devices == nil {
+			wrtr.WriteHeader(http.StatusNotFound)
+			_, _ = wrtr.Write([]byte("admin server did not recognise the device: '" + deviceName + "'"))
 
-	defer func() {
-		closeErr := resp.Body.Close()
-		if err == nil {
-			err = closeErr
+			return
 		}
-	}()
+	}
 
-	if utils.ResponseStatusIsNot2xx(resp.StatusCode) {
-		slog.Error("awtrix has responded to reboot command with non-2xx http response",
-			"http-status", resp.Status)
+	errs := make([]error, len(devices))
+
+	var dismissing sync.WaitGroup
+
+	for i, dvc := range devices {
+		dismissing.Add(1)
+
+		go func() {
+			defer dismissing.Done()
+
+			errs[i] = b.dismissNotification(ctx, dvc)
+		}()
 	}
 
-	return err
-}
+	dismissing.Wait()
+
+	err := errors.Join(errs...)
+	if err != nil {
+		slog.Error("admin server failed to dismiss notification", "error", err)
+		wrtr.WriteHeader(http.StatusBadGateway)
+		_, _ = wrtr.Write([]byte(err.Error()))
 
-func mergeConfig(left awtrix.Config, right awtrix.Config) awtrix.Config {
-	keep := awtrix.Config{}
-	if right.Overlay != "" {
-		keep.Overlay = right.Overlay
-	} else if left.Overlay != "" {
-		keep.Overlay = left.Overlay
+		return
 	}
 
-	return keep
+	slog.Info("admin server dismissed notification", "device", deviceName)
+	wrtr.WriteHeader(http.StatusOK)
 }
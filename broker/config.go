@@ -1,6 +1,8 @@
 package broker
 
 import (
+	"time"
+
 	"github.com/t-monaghan/altar/utils/awtrix"
 )
 
@@ -29,7 +31,7 @@ func DisableDefaultTimeApp() func(*awtrix.Config) {
 	}
 }
 
-// DisableDefaultWeekdayApp disables the default time app on the awtrix device on broker startup.
+// DisableDefaultWeekdayApp disables the default weekday app on the awtrix device on broker startup.
 func DisableDefaultWeekdayApp() func(*awtrix.Config) {
 	return func(cfg *awtrix.Config) {
 		disable := false
@@ -68,3 +70,301 @@ func DisableDefaultBatteryApp() func(*awtrix.Config) {
 		cfg.BatteryAppEnabled = &disable
 	}
 }
+
+// maxVolume is the loudest volume supported by the Awtrix buzzer and DFplayer.
+const maxVolume = 30
+
+func rgb(red, green, blue uint8) []int {
+	return []int{int(red), int(green), int(blue)}
+}
+
+// SetBrightness sets the brightness of the awtrix matrix on broker startup.
+func SetBrightness(brightness uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		level := int(brightness)
+		cfg.Brightness = &level
+	}
+}
+
+// EnableAutoBrightness has the awtrix device control its brightness using its light sensor.
+func EnableAutoBrightness() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		enable := true
+		cfg.AutoBrightness = &enable
+	}
+}
+
+// DisableAutoBrightness stops the awtrix device from controlling its brightness using its light sensor.
+func DisableAutoBrightness() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		disable := false
+		cfg.AutoBrightness = &disable
+	}
+}
+
+// SetAppDuration sets how long each app is displayed for, the awtrix device only supports whole seconds.
+func SetAppDuration(duration time.Duration) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		seconds := int(duration.Seconds())
+		cfg.AppDuration = &seconds
+	}
+}
+
+// EnableAutoTransition has the awtrix device automatically switch to the next app.
+func EnableAutoTransition() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		enable := true
+		cfg.AutoTransition = &enable
+	}
+}
+
+// DisableAutoTransition stops the awtrix device from automatically switching to the next app.
+func DisableAutoTransition() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		disable := false
+		cfg.AutoTransition = &disable
+	}
+}
+
+// SetTransitionEffect sets the effect used to transition between apps.
+func SetTransitionEffect(effect awtrix.TransitionEffect) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.TransitionEffect = &effect
+	}
+}
+
+// SetTransitionSpeed sets how long the transition between apps takes, the awtrix device only supports whole
+// milliseconds.
+func SetTransitionSpeed(duration time.Duration) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		milliseconds := int(duration.Milliseconds())
+		cfg.TransitionSpeed = &milliseconds
+	}
+}
+
+// SetScrollSpeed sets the speed text scrolls at as a percentage of the awtrix device's default scroll speed.
+func SetScrollSpeed(percentage int) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.ScrollSpeed = &percentage
+	}
+}
+
+// SetTextColour sets the global text colour of the awtrix device.
+func SetTextColour(red, green, blue uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.TextColour = rgb(red, green, blue)
+	}
+}
+
+// EnableUppercase has the awtrix device display all text in uppercase.
+func EnableUppercase() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		enable := true
+		cfg.Uppercase = &enable
+	}
+}
+
+// DisableUppercase has the awtrix device display text in the case it was sent in.
+func DisableUppercase() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		disable := false
+		cfg.Uppercase = &disable
+	}
+}
+
+// SetOverlay sets the global effect overlay of the awtrix device.
+func SetOverlay(overlay awtrix.Overlay) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.Overlay = overlay
+	}
+}
+
+// SetTimeMode sets the style of the default time app.
+func SetTimeMode(mode awtrix.TimeMode) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.TimeMode = &mode
+	}
+}
+
+// SetTimeFormat sets the format of the default time app, e.g. "%H:%M".
+//
+// https://blueforcer.github.io/awtrix3/#/api?id=time-format
+func SetTimeFormat(format string) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.TimeFormat = format
+	}
+}
+
+// SetDateFormat sets the format of the default date app, e.g. "%d.%m.%y".
+//
+// https://blueforcer.github.io/awtrix3/#/api?id=date-format
+func SetDateFormat(format string) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.DateFormat = format
+	}
+}
+
+// StartWeekOnMonday has the awtrix device's weekday display start the week on Monday.
+func StartWeekOnMonday() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		monday := true
+		cfg.StartOnMonday = &monday
+	}
+}
+
+// StartWeekOnSunday has the awtrix device's weekday display start the week on Sunday.
+func StartWeekOnSunday() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		monday := false
+		cfg.StartOnMonday = &monday
+	}
+}
+
+// ShowTemperatureInCelsius has the awtrix device display temperatures in Celsius.
+func ShowTemperatureInCelsius() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		celsius := true
+		cfg.Celsius = &celsius
+	}
+}
+
+// ShowTemperatureInFahrenheit has the awtrix device display temperatures in Fahrenheit.
+func ShowTemperatureInFahrenheit() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		celsius := false
+		cfg.Celsius = &celsius
+	}
+}
+
+// SetCalendarHeaderColour sets the colour of the calendar header in the default time app.
+func SetCalendarHeaderColour(red, green, blue uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.CalendarHeaderColour = rgb(red, green, blue)
+	}
+}
+
+// SetCalendarBodyColour sets the colour of the calendar body in the default time app.
+func SetCalendarBodyColour(red, green, blue uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.CalendarBodyColour = rgb(red, green, blue)
+	}
+}
+
+// SetCalendarTextColour sets the colour of the calendar text in the default time app.
+func SetCalendarTextColour(red, green, blue uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.CalendarTextColour = rgb(red, green, blue)
+	}
+}
+
+// SetWeekdayColours sets the colours of the current day and the other days in the weekday display.
+func SetWeekdayColours(active [3]uint8, inactive [3]uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.ActiveWeekdayColour = rgb(active[0], active[1], active[2])
+		cfg.InactiveWeekdayColour = rgb(inactive[0], inactive[1], inactive[2])
+	}
+}
+
+// SetTimeAppColour sets the text colour of the default time app.
+func SetTimeAppColour(red, green, blue uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.TimeAppColour = rgb(red, green, blue)
+	}
+}
+
+// SetDateAppColour sets the text colour of the default date app.
+func SetDateAppColour(red, green, blue uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.DateAppColour = rgb(red, green, blue)
+	}
+}
+
+// SetTempAppColour sets the text colour of the default temperature app.
+func SetTempAppColour(red, green, blue uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.TempAppColour = rgb(red, green, blue)
+	}
+}
+
+// SetHumidityAppColour sets the text colour of the default humidity app.
+func SetHumidityAppColour(red, green, blue uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.HumidityAppColour = rgb(red, green, blue)
+	}
+}
+
+// SetBatteryAppColour sets the text colour of the default battery app.
+func SetBatteryAppColour(red, green, blue uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.BatteryAppColour = rgb(red, green, blue)
+	}
+}
+
+// SetColourCorrection sets the colour correction applied to the awtrix matrix.
+func SetColourCorrection(red, green, blue uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.ColourCorrection = rgb(red, green, blue)
+	}
+}
+
+// SetColourTemperature sets the colour temperature applied to the awtrix matrix.
+func SetColourTemperature(red, green, blue uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.ColourTemperature = rgb(red, green, blue)
+	}
+}
+
+// SetGamma sets the gamma of the awtrix matrix.
+func SetGamma(gamma float64) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.Gamma = &gamma
+	}
+}
+
+// SetMatrixLayout sets the wiring layout of the awtrix matrix.
+func SetMatrixLayout(layout awtrix.MatrixLayout) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		cfg.MatrixLayout = &layout
+	}
+}
+
+// BlockNavigationKeys stops the awtrix device's physical buttons from navigating between apps, button presses are
+// still reported.
+func BlockNavigationKeys() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		block := true
+		cfg.BlockNavigationKeys = &block
+	}
+}
+
+// UnblockNavigationKeys allows the awtrix device's physical buttons to navigate between apps.
+func UnblockNavigationKeys() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		block := false
+		cfg.BlockNavigationKeys = &block
+	}
+}
+
+// EnableSound allows the awtrix device to play sounds.
+func EnableSound() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		enable := true
+		cfg.SoundEnabled = &enable
+	}
+}
+
+// MuteSound globally mutes the awtrix device.
+func MuteSound() func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		disable := false
+		cfg.SoundEnabled = &disable
+	}
+}
+
+// SetVolume sets the volume of the awtrix device's buzzer or DFplayer, volumes above 30 are capped at 30.
+func SetVolume(volume uint8) func(*awtrix.Config) {
+	return func(cfg *awtrix.Config) {
+		level := min(int(volume), maxVolume)
+		cfg.Volume = &level
+	}
+}
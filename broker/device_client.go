@@ -0,0 +1,81 @@
+package broker
+
+import (
+	"context"
+
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// httpDeviceClient talks to a device over its HTTP API using the broker's client. The device's address is looked up
+// on every request, so the client follows a device whose address is re-resolved.
+type httpDeviceClient struct {
+	broker *HTTPBroker
+	device *device
+}
+
+func (c *httpDeviceClient) client() (*awtrix.HTTPClient, error) {
+	address, credentials, err := c.broker.awtrixAddress(c.device, "")
+	if err != nil {
+		return nil, err
+	}
+
+	client := &awtrix.HTTPClient{BaseURL: address, HTTP: c.broker.Client}
+
+	if credentials != nil {
+		client.Username = credentials.Username()
+		client.Password, _ = credentials.Password()
+	}
+
+	return client, nil
+}
+
+// Send makes a request to the endpoint on the device.
+func (c *httpDeviceClient) Send(ctx context.Context, endpoint awtrix.Endpoint, payload []byte) error {
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	return client.Send(ctx, endpoint, payload) //nolint:wrapcheck // HTTPClient's errors describe the request
+}
+
+// Stats requests the device's stats.
+func (c *httpDeviceClient) Stats(ctx context.Context) (awtrix.Stats, error) {
+	client, err := c.client()
+	if err != nil {
+		return awtrix.Stats{}, err
+	}
+
+	return client.Stats(ctx) //nolint:wrapcheck // HTTPClient's errors describe the request
+}
+
+// SetSettings changes the device's settings.
+func (c *httpDeviceClient) SetSettings(ctx context.Context, cfg awtrix.Config) error {
+	return awtrix.SendJSON(ctx, c, awtrix.SettingsEndpoint(), cfg) //nolint:wrapcheck // wrapped by SendJSON
+}
+
+// PushApp creates or updates the custom app named name.
+func (c *httpDeviceClient) PushApp(ctx context.Context, name string, app any) error {
+	return awtrix.SendJSON(ctx, c, awtrix.CustomAppEndpoint(name), app) //nolint:wrapcheck // wrapped by SendJSON
+}
+
+// Notify shows a notification on the device.
+func (c *httpDeviceClient) Notify(ctx context.Context, notification any) error {
+	return awtrix.SendJSON(ctx, c, awtrix.NotifyEndpoint(), notification) //nolint:wrapcheck // wrapped by SendJSON
+}
+
+// DismissNotification dismisses the notification the device is showing.
+func (c *httpDeviceClient) DismissNotification(ctx context.Context) error {
+	return c.Send(ctx, awtrix.DismissNotificationEndpoint(), nil)
+}
+
+// SetIndicator sets the state of one of the device's indicators.
+func (c *httpDeviceClient) SetIndicator(ctx context.Context, indicator int, state any) error {
+	//nolint:wrapcheck // wrapped by SendJSON
+	return awtrix.SendJSON(ctx, c, awtrix.IndicatorEndpoint(indicator), state)
+}
+
+// Reboot restarts the device.
+func (c *httpDeviceClient) Reboot(ctx context.Context) error {
+	return c.Send(ctx, awtrix.RebootEndpoint(), nil)
+}
@@ -0,0 +1,141 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// DefaultHealthCheckInterval is the default interval between the broker's checks of the Awtrix device's stats.
+const DefaultHealthCheckInterval = 30 * time.Second
+
+// ErrMalformedStats occurs when the Awtrix device responds to a stats request with a body that is not valid stats.
+var ErrMalformedStats = awtrix.ErrMalformedStats
+
+// ErrResolveWithoutHealthChecks occurs when a device has a resolver while health checks are disabled, as the broker
+// only re-resolves a device when a health check finds it offline.
+var ErrResolveWithoutHealthChecks = errors.New("a device with a resolver requires health checks")
+
+// validateHealthConfig checks that every device with a resolver will be re-resolved once it goes offline.
+func (b *HTTPBroker) validateHealthConfig() error {
+	if b.HealthCheckInterval >= 0 {
+		return nil
+	}
+
+	for _, dvc := range b.devices {
+		if dvc.resolve != nil {
+			return fmt.Errorf("%w: device %v has a resolver but HealthCheckInterval is negative",
+				ErrResolveWithoutHealthChecks, dvc.name)
+		}
+	}
+
+	return nil
+}
+
+// deviceHealth tracks what the broker last observed of an Awtrix device.
+type deviceHealth struct {
+	device   string
+	observed bool
+	online   bool
+	uptime   uint64
+}
+
+// observe records the outcome of a health check, reporting whether the device has rebooted or come back online since
+// the previous check.
+func (h *deviceHealth) observe(stats awtrix.Stats, err error) bool {
+	if err != nil {
+		if h.online || !h.observed {
+			slog.Warn("awtrix device is offline", "device", h.device, "error", err)
+		}
+
+		h.observed = true
+		h.online = false
+
+		return false
+	}
+
+	returned := h.observed && !h.online
+	rebooted := h.observed && h.online && stats.Uptime < h.uptime
+
+	switch {
+	case returned:
+		slog.Info("awtrix device is back online", "device", h.device, "uptime-seconds", stats.Uptime)
+	case rebooted:
+		slog.Info("awtrix device has rebooted", "device", h.device, "uptime-seconds", stats.Uptime,
+			"previous-uptime-seconds", h.uptime)
+	}
+
+	h.observed = true
+	h.online = true
+	h.uptime = stats.Uptime
+
+	return returned || rebooted
+}
+
+// monitorHealth polls a device's stats until ctx is done, re-provisioning the device whenever it reboots or returns
+// from being offline. A device with a resolver is re-resolved while it is offline.
+func (b *HTTPBroker) monitorHealth(ctx context.Context, dvc *device) {
+	if b.HealthCheckInterval < 0 {
+		return
+	}
+
+	interval := b.HealthCheckInterval
+	if interval == 0 {
+		interval = DefaultHealthCheckInterval
+	}
+
+	health := deviceHealth{device: dvc.name}
+	ticker := time.NewTicker(interval)
+
+	defer ticker.Stop()
+
+	for {
+		stats, err := dvc.client.Stats(ctx)
+		if errors.Is(err, ErrMalformedStats) {
+			// a device that responds is reachable, but without an uptime a reboot cannot be detected
+			slog.Debug("skipping awtrix health check", "error", err)
+		} else if ctx.Err() == nil && health.observe(stats, err) {
+			b.reprovision(ctx, dvc)
+		}
+
+		if err != nil && !errors.Is(err, ErrMalformedStats) && ctx.Err() == nil {
+			moved, resolveErr := dvc.resolveAddress(ctx)
+			if resolveErr != nil {
+				slog.Warn("error re-resolving the awtrix device", "device", dvc.name, "error", resolveErr)
+			} else if moved {
+				// check the new address straight away, re-provisioning the device once it is found online
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reprovision restores the state a device loses when it power-cycles, re-sending its display configuration and
+// having every application it displays push its last data.
+func (b *HTTPBroker) reprovision(ctx context.Context, dvc *device) {
+	slog.Info("re-provisioning awtrix device", "device", dvc.name)
+
+	dvc.configMu.Lock()
+	err := b.sendConfig(ctx, dvc)
+	dvc.configMu.Unlock()
+
+	if err != nil {
+		slog.Error("error re-sending awtrix configuration", "device", dvc.name, "error", err)
+	}
+
+	for _, scheduled := range b.scheduled {
+		if dvc.displays(scheduled.routine.GetName()) {
+			scheduled.queueRepush(dvc)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package broker
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// configRequest is the configuration a routine has asked the broker to apply to the Awtrix device.
+type configRequest struct {
+	routine string
+	config  awtrix.Config
+}
+
+// configConflict describes two routines requesting different values for the same setting.
+type configConflict struct {
+	setting string
+	kept    string
+	ignored string
+}
+
+func (c configConflict) String() string {
+	return fmt.Sprintf("%v:%v:%v", c.setting, c.kept, c.ignored)
+}
+
+// mergeConfig merges the configuration requested by each routine over the broker's base configuration, field by
+// field. Routines take precedence over the base configuration, and when routines conflict the routine listed first
+// in the broker takes precedence.
+func mergeConfig(base awtrix.Config, requests []configRequest) (awtrix.Config, []configConflict) {
+	merged := awtrix.Config{}
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	setBy := make([]string, mergedValue.NumField())
+	conflicts := []configConflict{}
+
+	for _, request := range requests {
+		requestValue := reflect.ValueOf(request.config)
+
+		for i := range requestValue.NumField() {
+			field := requestValue.Field(i)
+			if field.IsZero() {
+				continue
+			}
+
+			if setBy[i] == "" {
+				mergedValue.Field(i).Set(field)
+				setBy[i] = request.routine
+
+				continue
+			}
+
+			if !reflect.DeepEqual(mergedValue.Field(i).Interface(), field.Interface()) {
+				conflicts = append(conflicts, configConflict{
+					setting: settingName(mergedValue.Type().Field(i)),
+					kept:    setBy[i],
+					ignored: request.routine,
+				})
+			}
+		}
+	}
+
+	baseValue := reflect.ValueOf(base)
+
+	for i := range baseValue.NumField() {
+		if setBy[i] == "" && !baseValue.Field(i).IsZero() {
+			mergedValue.Field(i).Set(baseValue.Field(i))
+		}
+	}
+
+	return merged, conflicts
+}
+
+// restoreWithdrawnConfig restores settings that were previously applied but are no longer requested by any routine
+// or the broker. Settings are restored to the Awtrix device's defaults, settings without a known default are left as
+// they are on the device.
+func restoreWithdrawnConfig(previous awtrix.Config, next awtrix.Config) awtrix.Config {
+	defaults := reflect.ValueOf(awtrix.DefaultConfig())
+	previousValue := reflect.ValueOf(previous)
+	nextValue := reflect.ValueOf(&next).Elem()
+
+	for i := range nextValue.NumField() {
+		if previousValue.Field(i).IsZero() || !nextValue.Field(i).IsZero() {
+			continue
+		}
+
+		setting := settingName(nextValue.Type().Field(i))
+
+		if defaults.Field(i).IsZero() {
+			slog.Debug("awtrix setting is no longer requested but has no default to restore", "setting", setting)
+
+			continue
+		}
+
+		slog.Debug("restoring awtrix setting to its default", "setting", setting)
+		nextValue.Field(i).Set(defaults.Field(i))
+	}
+
+	return next
+}
+
+// settingName returns the Awtrix key for a field of awtrix.Config.
+func settingName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+	return name
+}
@@ -0,0 +1,400 @@
+package broker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// DropCounter is implemented by the queues behind handlers, such as an inbox.Inbox, that drop messages while full.
+type DropCounter interface {
+	Dropped() uint64
+}
+
+// fetchDurationBuckets are the upper bounds, in seconds, of the fetch duration histogram's buckets.
+var fetchDurationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// schedulerLagBuckets are the upper bounds, in seconds, of the scheduler lag histogram's buckets.
+var schedulerLagBuckets = []float64{0.001, 0.01, 0.1, 0.5, 1, 5, 15, 60}
+
+// histogram counts observations into cumulative buckets as Prometheus expects.
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]uint64, len(bounds))}
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.bounds {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += value
+	h.count++
+}
+
+// pushTarget identifies an endpoint of one of the broker's devices.
+type pushTarget struct {
+	device   string
+	endpoint string
+}
+
+// labels formats the target as Prometheus labels.
+func (t pushTarget) labels() string {
+	return "device=" + quoteLabel(t.device) + ",endpoint=" + quoteLabel(t.endpoint)
+}
+
+func comparePushTargets(a, b pushTarget) int {
+	if a.device != b.device {
+		return strings.Compare(a.device, b.device)
+	}
+
+	return strings.Compare(a.endpoint, b.endpoint)
+}
+
+// pushStatus identifies the responses with a status received from a device's endpoint.
+type pushStatus struct {
+	pushTarget
+
+	status int
+}
+
+// metrics records the broker's activity, it is served in Prometheus' text format by the admin server and is safe for
+// concurrent use.
+type metrics struct {
+	mu                 sync.Mutex
+	fetchDurations     map[string]*histogram
+	fetchErrors        map[string]uint64
+	fetchPanics        map[string]uint64
+	schedulerLag       map[string]*histogram
+	lastRoutineSuccess map[string]time.Time
+	pushAttempts       map[pushTarget]uint64
+	pushFailures       map[pushTarget]uint64
+	pushStatuses       map[pushStatus]uint64
+	lastPushSuccess    map[pushTarget]time.Time
+}
+
+// newMetrics creates metrics reporting every routine named in routineNames, so their series exist before they run.
+func newMetrics(routineNames []string) *metrics {
+	m := &metrics{
+		fetchDurations:     map[string]*histogram{},
+		fetchErrors:        map[string]uint64{},
+		fetchPanics:        map[string]uint64{},
+		schedulerLag:       map[string]*histogram{},
+		lastRoutineSuccess: map[string]time.Time{},
+		pushAttempts:       map[pushTarget]uint64{},
+		pushFailures:       map[pushTarget]uint64{},
+		pushStatuses:       map[pushStatus]uint64{},
+		lastPushSuccess:    map[pushTarget]time.Time{},
+	}
+
+	for _, name := range routineNames {
+		m.fetchDurations[name] = newHistogram(fetchDurationBuckets)
+		m.fetchErrors[name] = 0
+		m.fetchPanics[name] = 0
+		m.schedulerLag[name] = newHistogram(schedulerLagBuckets)
+	}
+
+	return m
+}
+
+// observeFetch records a routine's fetch that took duration and failed with err, if it failed.
+func (m *metrics) observeFetch(routine string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.fetchDurations[routine].observe(duration.Seconds())
+
+	switch {
+	case errors.Is(err, ErrFetcherPanicked):
+		m.fetchPanics[routine]++
+	case err != nil:
+		m.fetchErrors[routine]++
+	}
+}
+
+// observeLag records how long after it was due a routine's run started.
+func (m *metrics) observeLag(routine string, lag time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.schedulerLag[routine].observe(max(lag, 0).Seconds())
+}
+
+// observeRun records the outcome of a routine's run, a run succeeds when it fetches and pushes without error.
+func (m *metrics) observeRun(routine string, err error) {
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastRoutineSuccess[routine] = time.Now()
+}
+
+// observePush records an attempt to send a request to a device's endpoint, which failed with err if it failed.
+func (m *metrics) observePush(device string, endpoint string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	target := pushTarget{device: device, endpoint: endpoint}
+	m.pushAttempts[target]++
+
+	if err == nil {
+		m.lastPushSuccess[target] = time.Now()
+
+		return
+	}
+
+	m.pushFailures[target]++
+
+	var statusErr *awtrix.StatusError
+	if errors.As(err, &statusErr) {
+		m.pushStatuses[pushStatus{pushTarget: target, status: statusErr.StatusCode}]++
+	}
+}
+
+// metricsHandler serves the broker's metrics in Prometheus' text format.
+func (b *HTTPBroker) metricsHandler(wrtr http.ResponseWriter, _ *http.Request) {
+	var out bytes.Buffer
+
+	b.metrics.write(&out)
+	b.writeDropped(&out)
+
+	wrtr.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	wrtr.WriteHeader(http.StatusOK)
+
+	_, err := wrtr.Write(out.Bytes())
+	if err != nil {
+		slog.Error("admin server failed to write metrics", "error", err)
+	}
+}
+
+func (m *metrics) write(out *bytes.Buffer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeHeader(out, "altar_fetch_duration_seconds", "histogram", "Time taken by each routine's fetch.")
+
+	for _, routine := range sortedKeys(m.fetchDurations) {
+		writeHistogram(out, "altar_fetch_duration_seconds", "routine", routine, m.fetchDurations[routine])
+	}
+
+	writeCounters(out, "altar_fetch_errors_total", "Fetches that returned an error, per routine.", "routine",
+		m.fetchErrors)
+	writeCounters(out, "altar_fetch_panics_total", "Fetches that panicked, per routine.", "routine", m.fetchPanics)
+
+	writeHeader(out, "altar_scheduler_lag_seconds", "histogram",
+		"Time between each routine's run falling due and starting.")
+
+	for _, routine := range sortedKeys(m.schedulerLag) {
+		writeHistogram(out, "altar_scheduler_lag_seconds", "routine", routine, m.schedulerLag[routine])
+	}
+
+	writeTimestamps(out, "altar_routine_last_success_timestamp_seconds",
+		"Time each routine last fetched and pushed without error.", "routine", m.lastRoutineSuccess)
+
+	writeHeader(out, "altar_push_attempts_total", "counter", "Requests sent to devices, per device and endpoint.")
+
+	for _, target := range sortedTargets(m.pushAttempts) {
+		fmt.Fprintf(out, "altar_push_attempts_total{%v} %d\n", target.labels(), m.pushAttempts[target])
+	}
+
+	writeHeader(out, "altar_push_failures_total", "counter",
+		"Requests to devices that failed, per device and endpoint.")
+
+	for _, target := range sortedTargets(m.pushFailures) {
+		fmt.Fprintf(out, "altar_push_failures_total{%v} %d\n", target.labels(), m.pushFailures[target])
+	}
+
+	writeHeader(out, "altar_push_error_responses_total", "counter",
+		"Requests to devices answered with a non-2xx status, per device, endpoint and status.")
+
+	statuses := make([]pushStatus, 0, len(m.pushStatuses))
+	for status := range m.pushStatuses {
+		statuses = append(statuses, status)
+	}
+
+	slices.SortFunc(statuses, func(a, b pushStatus) int {
+		if order := comparePushTargets(a.pushTarget, b.pushTarget); order != 0 {
+			return order
+		}
+
+		return a.status - b.status
+	})
+
+	for _, status := range statuses {
+		fmt.Fprintf(out, "altar_push_error_responses_total{%v,status=\"%d\"} %d\n",
+			status.labels(), status.status, m.pushStatuses[status])
+	}
+
+	writeHeader(out, "altar_push_last_success_timestamp_seconds", "gauge",
+		"Time each device's endpoint last accepted a request.")
+
+	for _, target := range sortedTargets(m.lastPushSuccess) {
+		fmt.Fprintf(out, "altar_push_last_success_timestamp_seconds{%v} %v\n", target.labels(),
+			formatTimestamp(m.lastPushSuccess[target]))
+	}
+}
+
+// writeDropped reports the messages dropped by the queue behind each handler in DropCounters.
+func (b *HTTPBroker) writeDropped(out *bytes.Buffer) {
+	dropped := make(map[string]uint64, len(b.DropCounters))
+	for path, counter := range b.DropCounters {
+		dropped[path] = counter.Dropped()
+	}
+
+	writeCounters(out, "altar_handler_dropped_messages_total",
+		"Messages received by handlers and dropped because their queue was full, per handler path.", "path", dropped)
+}
+
+func writeHeader(out *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(out, "# HELP %v %v\n# TYPE %v %v\n", name, help, name, kind)
+}
+
+func writeCounters(out *bytes.Buffer, name, help, label string, counters map[string]uint64) {
+	writeHeader(out, name, "counter", help)
+
+	for _, value := range sortedKeys(counters) {
+		fmt.Fprintf(out, "%v{%v=%v} %d\n", name, label, quoteLabel(value), counters[value])
+	}
+}
+
+func writeTimestamps(out *bytes.Buffer, name, help, label string, timestamps map[string]time.Time) {
+	writeHeader(out, name, "gauge", help)
+
+	for _, value := range sortedKeys(timestamps) {
+		fmt.Fprintf(out, "%v{%v=%v} %v\n", name, label, quoteLabel(value), formatTimestamp(timestamps[value]))
+	}
+}
+
+func writeHistogram(out *bytes.Buffer, name, label, value string, hist *histogram) {
+	labels := label + "=" + quoteLabel(value)
+
+	for i, bound := range hist.bounds {
+		fmt.Fprintf(out, "%v_bucket{%v,le=\"%v\"} %d\n", name, labels, formatFloat(bound), hist.counts[i])
+	}
+
+	fmt.Fprintf(out, "%v_bucket{%v,le=\"+Inf\"} %d\n", name, labels, hist.count)
+	fmt.Fprintf(out, "%v_sum{%v} %v\n", name, labels, formatFloat(hist.sum))
+	fmt.Fprintf(out, "%v_count{%v} %d\n", name, labels, hist.count)
+}
+
+// labelEscaper escapes label values as Prometheus' text format expects.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// formatTimestamp formats a time as the seconds since the Unix epoch.
+func formatTimestamp(value time.Time) string {
+	return formatFloat(float64(value.UnixMilli()) / float64(time.Second/time.Millisecond))
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+func sortedTargets[V any](values map[pushTarget]V) []pushTarget {
+	targets := make([]pushTarget, 0, len(values))
+	for target := range values {
+		targets = append(targets, target)
+	}
+
+	slices.SortFunc(targets, comparePushTargets)
+
+	return targets
+}
+
+// meteredClient records the requests made to a device's endpoints in the broker's metrics.
+type meteredClient struct {
+	awtrix.Client
+	device  string
+	metrics *metrics
+}
+
+// Send delivers payload to the device's endpoint.
+func (c *meteredClient) Send(ctx context.Context, endpoint awtrix.Endpoint, payload []byte) error {
+	err := c.Client.Send(ctx, endpoint, payload)
+	c.metrics.observePush(c.device, endpoint.Path, err)
+
+	return err //nolint:wrapcheck // the device client's errors describe the request
+}
+
+// SetSettings changes the device's settings.
+func (c *meteredClient) SetSettings(ctx context.Context, cfg awtrix.Config) error {
+	err := c.Client.SetSettings(ctx, cfg)
+	c.metrics.observePush(c.device, awtrix.SettingsEndpoint().Path, err)
+
+	return err //nolint:wrapcheck // the device client's errors describe the request
+}
+
+// PushApp creates or updates the custom app named name.
+func (c *meteredClient) PushApp(ctx context.Context, name string, app any) error {
+	err := c.Client.PushApp(ctx, name, app)
+	c.metrics.observePush(c.device, awtrix.CustomAppEndpoint(name).Path, err)
+
+	return err //nolint:wrapcheck // the device client's errors describe the request
+}
+
+// Notify shows a notification on the device.
+func (c *meteredClient) Notify(ctx context.Context, notification any) error {
+	err := c.Client.Notify(ctx, notification)
+	c.metrics.observePush(c.device, awtrix.NotifyEndpoint().Path, err)
+
+	return err //nolint:wrapcheck // the device client's errors describe the request
+}
+
+// DismissNotification dismisses the notification the device is showing.
+func (c *meteredClient) DismissNotification(ctx context.Context) error {
+	err := c.Client.DismissNotification(ctx)
+	c.metrics.observePush(c.device, awtrix.DismissNotificationEndpoint().Path, err)
+
+	return err //nolint:wrapcheck // the device client's errors describe the request
+}
+
+// SetIndicator sets the state of one of the device's indicators.
+func (c *meteredClient) SetIndicator(ctx context.Context, indicator int, state any) error {
+	err := c.Client.SetIndicator(ctx, indicator, state)
+	c.metrics.observePush(c.device, awtrix.IndicatorEndpoint(indicator).Path, err)
+
+	return err //nolint:wrapcheck // the device client's errors describe the request
+}
+
+// Reboot restarts the device.
+func (c *meteredClient) Reboot(ctx context.Context) error {
+	err := c.Client.Reboot(ctx)
+	c.metrics.observePush(c.device, awtrix.RebootEndpoint().Path, err)
+
+	return err //nolint:wrapcheck // the device client's errors describe the request
+}
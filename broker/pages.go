@@ -0,0 +1,163 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/t-monaghan/altar/utils"
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// pagesOn returns the number of a routine's pages that may be on dvc.
+func (s *scheduledRoutine) pagesOn(dvc *device) int {
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
+
+	return s.pages[dvc]
+}
+
+func (s *scheduledRoutine) setPagesOn(dvc *device, pages int) {
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
+
+	s.pages[dvc] = pages
+}
+
+// pagedOn reports whether the routine's single app has been removed from dvc in favour of its pages.
+func (s *scheduledRoutine) pagedOn(dvc *device) bool {
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
+
+	return s.paged[dvc]
+}
+
+func (s *scheduledRoutine) setPagedOn(dvc *device, paged bool) {
+	s.pagesMu.Lock()
+	defer s.pagesMu.Unlock()
+
+	s.paged[dvc] = paged
+}
+
+// pushPages pushes a routine's payload to dvc, then removes the routine's pages that are no longer in its data from
+// the device. A routine that pushes a single app, or whose app is being removed, has no pages. A change in the number
+// of pages on the device is recorded in the broker's state file.
+func (b *HTTPBroker) pushPages(
+	ctx context.Context,
+	dvc *device,
+	scheduled *scheduledRoutine,
+	endpoint awtrix.Endpoint,
+	payload []byte,
+	force bool,
+) error {
+	previous := scheduled.pagesOn(dvc)
+
+	err := b.pushAndRemovePages(ctx, dvc, scheduled, endpoint, payload, force, previous)
+
+	if scheduled.pagesOn(dvc) != previous {
+		b.saveState()
+	}
+
+	return err
+}
+
+func (b *HTTPBroker) pushAndRemovePages(
+	ctx context.Context,
+	dvc *device,
+	scheduled *scheduledRoutine,
+	endpoint awtrix.Endpoint,
+	payload []byte,
+	force bool,
+	previous int,
+) error {
+	routine := scheduled.routine
+	name := endpoint.Query.Get("name")
+
+	paginator, canPage := routine.(utils.Paginator)
+	if !canPage || endpoint.Path != awtrix.CustomAppEndpoint("").Path || len(payload) == 0 {
+		err := b.push(ctx, dvc, routine, endpoint, payload, force)
+		if err != nil {
+			return err
+		}
+
+		scheduled.setPagedOn(dvc, false)
+
+		return b.removePages(ctx, dvc, scheduled, name, 0, previous)
+	}
+
+	pages, paged := paginator.PageCount()
+	if !paged {
+		pages = 0
+	}
+
+	if paged && !scheduled.pagedOn(dvc) && scheduled.pushed[dvc] {
+		// the routine has switched from a single app to pages, the single app is named without an index
+		err := b.removeApp(ctx, dvc, name)
+		if err != nil {
+			return err
+		}
+
+		dvc.delivered.forget(pushCacheKey(routine, endpoint))
+	}
+
+	scheduled.setPagedOn(dvc, paged)
+
+	if paged && pages == 0 {
+		// the device has no app to show for an empty list of pages, so only the stale pages are removed, and the
+		// pages are pushed again once they return even if they are the pages last delivered
+		dvc.delivered.forget(pushCacheKey(routine, endpoint))
+	} else {
+		err := b.push(ctx, dvc, routine, endpoint, payload, force)
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.removePages(ctx, dvc, scheduled, name, pages, max(previous, pages))
+}
+
+// removePages removes the pages of a routine's app numbered from first up to, but not including, last from dvc. The
+// pages that could not be removed are recorded as still being on the device, so they are removed on the next push.
+func (b *HTTPBroker) removePages(
+	ctx context.Context,
+	dvc *device,
+	scheduled *scheduledRoutine,
+	name string,
+	first int,
+	last int,
+) error {
+	var removeErr error
+
+	remaining := first
+
+	for page := first; page < last; page++ {
+		err := b.removeApp(ctx, dvc, name+strconv.Itoa(page))
+		if err != nil {
+			removeErr = errors.Join(removeErr, err)
+			remaining = page + 1
+		}
+	}
+
+	scheduled.setPagesOn(dvc, remaining)
+
+	return removeErr
+}
+
+// removeApp removes the custom app named name from dvc. It bypasses the device's payload cache, as the app's name may
+// also be the endpoint a routine pushes its pages to.
+func (b *HTTPBroker) removeApp(ctx context.Context, dvc *device, name string) error {
+	endpoint := awtrix.CustomAppEndpoint(name)
+
+	err := b.withRetries(ctx, dvc, endpoint, func(ctx context.Context, client awtrix.Client) error {
+		return client.Send(ctx, endpoint, []byte{})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove %v from %v: %w", name, dvc.name, err)
+	}
+
+	slog.Debug("removed app", "app", name, "device", dvc.name)
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package broker
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/t-monaghan/altar/utils"
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// payloadCache remembers a hash of the last payload delivered to each routine's endpoint, allowing the broker to skip
+// pushes that would not change what the Awtrix device displays.
+type payloadCache struct {
+	mu     sync.Mutex
+	hashes map[string][sha256.Size]byte
+}
+
+func payloadCacheKey(routineName string, address string) string {
+	return routineName + "\x00" + address
+}
+
+// pushCacheKey returns the key of the payload a routine delivers to endpoint.
+func pushCacheKey(routine utils.Routine, endpoint awtrix.Endpoint) string {
+	return payloadCacheKey(routine.GetName(), endpoint.RequestMethod()+" "+endpoint.RequestURI())
+}
+
+// unchanged reports whether payload is identical to the last payload stored for key.
+func (c *payloadCache) unchanged(key string, payload []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	last, ok := c.hashes[key]
+
+	return ok && last == sha256.Sum256(payload)
+}
+
+// store records payload as the last payload delivered for key.
+func (c *payloadCache) store(key string, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.hashes == nil {
+		c.hashes = map[string][sha256.Size]byte{}
+	}
+
+	c.hashes[key] = sha256.Sum256(payload)
+}
+
+// forget removes the payload stored for key, so the next payload delivered for key is pushed whatever it contains.
+func (c *payloadCache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.hashes, key)
+}
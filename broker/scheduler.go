@@ -0,0 +1,385 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/t-monaghan/altar/utils"
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// scheduledRoutine holds the scheduling state of a single routine. Every routine is run on its own goroutine with its
+// own timer, so a slow or hung fetcher never delays the fetching or pushing of any other routine.
+type scheduledRoutine struct {
+	routine utils.Routine
+	// wake queues a run of the routine ahead of its timer, it is buffered so that a pending run is never lost.
+	wake chan struct{}
+	// push queues a forced push of the routine's last data to every device that displays it.
+	push chan struct{}
+	// repush queues a forced push of the routine's last data to the devices in pendingRepush, used to re-provision
+	// an Awtrix device after a reboot.
+	repush        chan struct{}
+	repushMu      sync.Mutex
+	pendingRepush map[*device]bool
+	// pushed is only accessed from the routine's goroutine, it records the devices the routine has pushed data to,
+	// or attempted to.
+	pushed map[*device]bool
+	// pages records the number of the routine's pages each device may be showing, so that pages which disappear
+	// between fetches can be removed. paged records the devices whose single app has been removed because the
+	// routine switched to pages.
+	pagesMu sync.Mutex
+	pages   map[*device]int
+	paged   map[*device]bool
+	// requestedConfig is the configuration the routine requested after its last fetch, guarded by the broker's
+	// requestsMu.
+	requestedConfig awtrix.Config
+	status          routineStatus
+}
+
+func newScheduledRoutine(routine utils.Routine) *scheduledRoutine {
+	return &scheduledRoutine{
+		routine:       routine,
+		wake:          make(chan struct{}, 1),
+		push:          make(chan struct{}, 1),
+		repush:        make(chan struct{}, 1),
+		pendingRepush: map[*device]bool{},
+		pushed:        map[*device]bool{},
+		pages:         map[*device]int{},
+		paged:         map[*device]bool{},
+	}
+}
+
+// queueRepush queues a forced push of the routine's last data to dvc.
+func (s *scheduledRoutine) queueRepush(dvc *device) {
+	s.repushMu.Lock()
+	s.pendingRepush[dvc] = true
+	s.repushMu.Unlock()
+
+	signal(s.repush)
+}
+
+// takeRepush returns the devices awaiting a forced push, clearing them.
+func (s *scheduledRoutine) takeRepush() []*device {
+	s.repushMu.Lock()
+	defer s.repushMu.Unlock()
+
+	devices := make([]*device, 0, len(s.pendingRepush))
+	for dvc := range s.pendingRepush {
+		devices = append(devices, dvc)
+	}
+
+	clear(s.pendingRepush)
+
+	return devices
+}
+
+// runRoutines runs every routine on its own schedule, returning once ctx is done and each routine has drained its
+// pending push.
+func (b *HTTPBroker) runRoutines(ctx context.Context) {
+	// pushes are drained after shutdown is requested, so they outlive the broker's context by the shutdown timeout
+	pushCtx, cancelPushes := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelPushes()
+
+	stopDeadline := context.AfterFunc(ctx, func() {
+		time.AfterFunc(b.shutdownTimeout(), cancelPushes)
+	})
+	defer stopDeadline()
+
+	var routineGroup sync.WaitGroup
+
+	for _, scheduled := range b.scheduled {
+		routineGroup.Add(1)
+
+		go func(scheduled *scheduledRoutine) {
+			defer routineGroup.Done()
+
+			b.runRoutine(ctx, pushCtx, scheduled)
+		}(scheduled)
+	}
+
+	routineGroup.Wait()
+}
+
+// runRoutine fetches and pushes a single routine each time its timer fires until ctx is done.
+func (b *HTTPBroker) runRoutine(ctx context.Context, pushCtx context.Context, scheduled *scheduledRoutine) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	due := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-scheduled.repush:
+			b.repush(pushCtx, scheduled)
+
+			continue
+		case <-scheduled.push:
+			b.pushLast(pushCtx, scheduled)
+
+			continue
+		case <-timer.C:
+			b.metrics.observeLag(scheduled.routine.GetName(), time.Since(due))
+		case <-scheduled.wake:
+			timer.Stop()
+
+			if waker, ok := scheduled.routine.(utils.Waker); ok {
+				waker.Wake()
+			}
+		}
+
+		if scheduled.status.isPaused() {
+			slog.Debug("skipping paused routine", "routine", scheduled.routine.GetName())
+		} else {
+			b.fetchAndPush(ctx, pushCtx, scheduled)
+		}
+
+		// the next run is measured from the end of this one, so the routine is always due when its timer fires
+		due = time.Now().Add(pollRateOf(scheduled.routine))
+		timer.Reset(time.Until(due))
+	}
+}
+
+// fetchAndPush fetches a routine's data once, then applies any configuration it requested and pushes its data to
+// every device that displays it as soon as the fetch has finished.
+func (b *HTTPBroker) fetchAndPush(ctx context.Context, pushCtx context.Context, scheduled *scheduledRoutine) {
+	routine := scheduled.routine
+	started := time.Now()
+
+	fetchErr := b.fetchRoutine(ctx, routine)
+	b.metrics.observeFetch(routine.GetName(), time.Since(started), fetchErr)
+
+	b.requestsMu.Lock()
+	scheduled.requestedConfig = routine.GetGlobalConfig()
+	b.requestsMu.Unlock()
+
+	shouldPush := routine.ShouldPushToAwtrix()
+	if !shouldPush {
+		slog.Debug("skipping push for routine", "routine", routine.GetName())
+	}
+
+	forcePusher, canForce := routine.(utils.ForcePusher)
+	force := canForce && forcePusher.ShouldForcePush()
+
+	dismisser, canDismiss := routine.(utils.Dismisser)
+	dismiss := canDismiss && dismisser.ShouldDismiss()
+
+	endpoint, payload, err := b.payloadOf(routine)
+	if err != nil && shouldPush {
+		slog.Error("error encountered pushing to awtrix device", "app", routine.GetName(), "error", err)
+
+		shouldPush = false
+		fetchErr = errors.Join(fetchErr, err)
+	}
+
+	pushErrs := b.deliverToDevices(b.devicesDisplaying(routine), scheduled, func(dvc *device) (bool, error) {
+		err := b.applyConfig(pushCtx, dvc, false)
+		if err != nil {
+			slog.Error("error changing awtrix settings", "routine", routine.GetName(), "device", dvc.name,
+				"error", err)
+		}
+
+		if dismiss {
+			err = b.dismissNotification(pushCtx, dvc)
+			if err != nil {
+				slog.Error("error dismissing notification", "routine", routine.GetName(), "error", err)
+			}
+		}
+
+		if !shouldPush {
+			return false, nil
+		}
+
+		err = b.pushPages(pushCtx, dvc, scheduled, endpoint, payload, force)
+		if err != nil {
+			slog.Error("error encountered pushing to awtrix device", "app", routine.GetName(), "error", err)
+		}
+
+		// a failed push is still owed to the device, it is re-pushed once the device is re-provisioned
+		return true, err
+	})
+
+	if shouldPush {
+		scheduled.status.recordPush(payload)
+	}
+
+	runErr := errors.Join(fetchErr, pushErrs)
+	scheduled.status.recordRun(started, runErr)
+	b.metrics.observeRun(routine.GetName(), runErr)
+}
+
+// pushLast forces a routine to push its last data to every device that displays it, regardless of whether it has
+// requested a push or its data is unchanged.
+func (b *HTTPBroker) pushLast(pushCtx context.Context, scheduled *scheduledRoutine) {
+	routine := scheduled.routine
+
+	endpoint, payload, err := b.payloadOf(routine)
+	if err != nil {
+		slog.Error("error encountered pushing to awtrix device", "app", routine.GetName(), "error", err)
+
+		return
+	}
+
+	err = b.deliverToDevices(b.devicesDisplaying(routine), scheduled, func(dvc *device) (bool, error) {
+		err := b.pushPages(pushCtx, dvc, scheduled, endpoint, payload, true)
+		if err != nil {
+			slog.Error("error encountered pushing to awtrix device", "app", routine.GetName(), "error", err)
+		}
+
+		return true, err
+	})
+	if err != nil {
+		return
+	}
+
+	scheduled.status.recordPush(payload)
+}
+
+// repush forces a routine with retained state, such as an application or indicator, to push its last data to the
+// devices awaiting it regardless of whether it has requested a push.
+func (b *HTTPBroker) repush(pushCtx context.Context, scheduled *scheduledRoutine) {
+	devices := scheduled.takeRepush()
+
+	// notifications are transient, re-sending them after a reboot would repeat them, and a removed app is already
+	// absent from a rebooted device
+	endpoint, payload, err := b.payloadOf(scheduled.routine)
+	if err != nil || !endpoint.Retained || len(payload) == 0 {
+		return
+	}
+
+	awaiting := make([]*device, 0, len(devices))
+
+	for _, dvc := range devices {
+		if scheduled.pushed[dvc] {
+			awaiting = append(awaiting, dvc)
+		}
+	}
+
+	_ = b.deliverToDevices(awaiting, scheduled, func(dvc *device) (bool, error) {
+		err := b.pushPages(pushCtx, dvc, scheduled, endpoint, payload, true)
+		if err != nil {
+			slog.Error("error re-pushing to awtrix device", "app", scheduled.routine.GetName(), "error", err)
+
+			return false, err
+		}
+
+		return true, nil
+	})
+}
+
+// payloadOf resolves the endpoint a routine pushes to and marshals its data.
+func (b *HTTPBroker) payloadOf(routine utils.Routine) (awtrix.Endpoint, []byte, error) {
+	endpointProvider, ok := routine.(utils.EndpointProvider)
+	if !ok {
+		return awtrix.Endpoint{}, nil, fmt.Errorf("%w for routine: %v", ErrUnknownRoutineType, routine.GetName())
+	}
+
+	endpoint, err := endpointProvider.Endpoint()
+	if err != nil {
+		return awtrix.Endpoint{}, nil, fmt.Errorf("failed to resolve awtrix endpoint for %v: %w",
+			routine.GetName(), err)
+	}
+
+	// an empty payload removes the routine's content, such as a custom app, from the device
+	if remover, ok := routine.(utils.Remover); ok && remover.ShouldRemove() {
+		return endpoint, []byte{}, nil
+	}
+
+	payload, err := json.Marshal(routine.GetData())
+	if err != nil {
+		return awtrix.Endpoint{}, nil, fmt.Errorf("failed to marshal %v data into json: %w", routine.GetName(), err)
+	}
+
+	return endpoint, payload, nil
+}
+
+// devicesDisplaying returns the devices that display routine.
+func (b *HTTPBroker) devicesDisplaying(routine utils.Routine) []*device {
+	devices := make([]*device, 0, len(b.devices))
+
+	for _, dvc := range b.devices {
+		if dvc.displays(routine.GetName()) {
+			devices = append(devices, dvc)
+		}
+	}
+
+	return devices
+}
+
+// deliverToDevices runs deliver against each device in parallel, so a slow or unreachable device never delays the
+// others, returning the errors it encounters. The devices deliver returns true for are recorded as pushed. It must only
+// be called from the routine's goroutine.
+func (b *HTTPBroker) deliverToDevices(
+	devices []*device,
+	scheduled *scheduledRoutine,
+	deliver func(*device) (bool, error),
+) error {
+	delivered := make([]bool, len(devices))
+	errs := make([]error, len(devices))
+
+	var deliveries sync.WaitGroup
+
+	for i, dvc := range devices {
+		deliveries.Add(1)
+
+		go func() {
+			defer deliveries.Done()
+
+			delivered[i], errs[i] = deliver(dvc)
+		}()
+	}
+
+	deliveries.Wait()
+
+	for i, dvc := range devices {
+		if delivered[i] {
+			scheduled.pushed[dvc] = true
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// fetchRoutine runs a routine's fetch bounded by the broker's fetch timeout. Requests made through the provided client
+// are bound to the fetch's context, so fetchers without a context are also cancelled.
+func (b *HTTPBroker) fetchRoutine(ctx context.Context, routine utils.Routine) (fetchErr error) {
+	timeout := b.FetchTimeout
+	if timeout <= 0 {
+		timeout = DefaultFetchTimeout
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("broker has recovered from fetcher panicking", "routine", routine.GetName(), "error", r)
+
+			fetchErr = fmt.Errorf("%w: %v", ErrFetcherPanicked, r)
+		}
+	}()
+
+	err := routine.Fetch(fetchCtx, clientWithContext(fetchCtx, b.Client))
+	if err != nil {
+		slog.Error("error encountered in fetching", "app", routine.GetName(), "error", err)
+
+		return fmt.Errorf("failed to fetch %v: %w", routine.GetName(), err)
+	}
+
+	return nil
+}
+
+func pollRateOf(routine utils.Routine) time.Duration {
+	pollRate := routine.GetPollRate()
+	if pollRate <= 0 {
+		return utils.DefaultPollRate
+	}
+
+	return pollRate
+}
@@ -0,0 +1,237 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/t-monaghan/altar/utils"
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// brokerState is the state the broker keeps between runs in its StateFile.
+type brokerState struct {
+	// Apps holds the names of the custom apps the broker owns on each device, keyed by the device's name.
+	Apps map[string][]string `json:"apps"`
+	// Pages holds the number of pages each of the broker's paged apps may have on each device, keyed by the device's
+	// name then the app's name. Page n of an app is a custom app named with the app's name followed by n.
+	Pages map[string]map[string]int `json:"pages,omitempty"`
+}
+
+// pagesOf returns the number of pages app may have on the device named deviceName.
+func (s brokerState) pagesOf(deviceName string, app string) int {
+	return s.Pages[deviceName][app]
+}
+
+// setPages records the number of pages app may have on the device named deviceName, apps without pages are omitted.
+func (s *brokerState) setPages(deviceName string, app string, pages int) {
+	if pages == 0 {
+		return
+	}
+
+	if s.Pages == nil {
+		s.Pages = map[string]map[string]int{}
+	}
+
+	if s.Pages[deviceName] == nil {
+		s.Pages[deviceName] = map[string]int{}
+	}
+
+	s.Pages[deviceName][app] = pages
+}
+
+// removeStaleApps removes the custom apps the broker owned when it last ran but no longer has a routine for, such as
+// the apps of routines that have been renamed or removed, along with their pages, then records the apps it now owns in
+// the state file. The pages of apps it still owns are removed by each routine's next push once they are no longer in
+// its data. Without a StateFile the broker cannot know which apps it owned, so it only warns that none are removed.
+func (b *HTTPBroker) removeStaleApps(ctx context.Context) {
+	if b.StateFile == "" {
+		slog.Warn("broker has no state file, apps of renamed or removed routines will not be removed from devices")
+
+		return
+	}
+
+	previous, err := readState(b.StateFile)
+	if err != nil {
+		slog.Error("error reading broker state, stale apps will not be removed", "file", b.StateFile, "error", err)
+
+		return
+	}
+
+	stranded := make([]map[string]int, len(b.devices))
+
+	var removing sync.WaitGroup
+
+	for i, dvc := range b.devices {
+		removing.Add(1)
+
+		go func() {
+			defer removing.Done()
+
+			owned := b.appsOwnedBy(dvc)
+			stranded[i] = map[string]int{}
+
+			for _, app := range previous.Apps[dvc.name] {
+				pages := previous.pagesOf(dvc.name, app)
+
+				if scheduled, ok := owned[app]; ok {
+					scheduled.setPagesOn(dvc, pages)
+
+					continue
+				}
+
+				slog.Info("removing stale app from awtrix device", "device", dvc.name, "app", app, "pages", pages)
+
+				err := b.removeStaleApp(ctx, dvc, app, pages)
+				if err != nil {
+					slog.Error("error removing stale app from awtrix device", "device", dvc.name, "app", app,
+						"error", err)
+
+					// the app is still owned, so its removal is retried the next time the broker starts
+					stranded[i][app] = pages
+				}
+			}
+		}()
+	}
+
+	removing.Wait()
+
+	b.stateMu.Lock()
+	b.strandedApps = make(map[string]map[string]int, len(b.devices))
+
+	for i, dvc := range b.devices {
+		b.strandedApps[dvc.name] = stranded[i]
+	}
+
+	b.stateMu.Unlock()
+
+	b.saveState()
+}
+
+// removeStaleApp removes the custom app named app and its first pages pages from dvc.
+func (b *HTTPBroker) removeStaleApp(ctx context.Context, dvc *device, app string, pages int) error {
+	errs := []error{b.removeApp(ctx, dvc, app)}
+
+	for page := range pages {
+		errs = append(errs, b.removeApp(ctx, dvc, app+strconv.Itoa(page)))
+	}
+
+	return errors.Join(errs...)
+}
+
+// saveState records the custom apps the broker owns on each device, and the pages each may have, in the state file. It
+// does nothing when the broker has no StateFile.
+func (b *HTTPBroker) saveState() {
+	if b.StateFile == "" {
+		return
+	}
+
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	state := brokerState{Apps: make(map[string][]string, len(b.devices))}
+
+	for _, dvc := range b.devices {
+		apps := []string{}
+
+		for app, scheduled := range b.appsOwnedBy(dvc) {
+			apps = append(apps, app)
+			state.setPages(dvc.name, app, scheduled.pagesOn(dvc))
+		}
+
+		for app, pages := range b.strandedApps[dvc.name] {
+			apps = append(apps, app)
+			state.setPages(dvc.name, app, pages)
+		}
+
+		slices.Sort(apps)
+		state.Apps[dvc.name] = apps
+	}
+
+	err := writeState(b.StateFile, state)
+	if err != nil {
+		slog.Error("error writing broker state", "file", b.StateFile, "error", err)
+	}
+}
+
+// appsOwnedBy returns the routines that push a custom app to dvc, keyed by the app's name.
+func (b *HTTPBroker) appsOwnedBy(dvc *device) map[string]*scheduledRoutine {
+	customAppPath := awtrix.CustomAppEndpoint("").Path
+	apps := map[string]*scheduledRoutine{}
+
+	for _, scheduled := range b.scheduled {
+		endpointProvider, ok := scheduled.routine.(utils.EndpointProvider)
+		if !ok || !dvc.displays(scheduled.routine.GetName()) {
+			continue
+		}
+
+		endpoint, err := endpointProvider.Endpoint()
+		if err != nil || endpoint.Path != customAppPath || !endpoint.Query.Has("name") {
+			continue
+		}
+
+		apps[endpoint.Query.Get("name")] = scheduled
+	}
+
+	return apps
+}
+
+// readState reads the broker's state from path, a missing file is read as an empty state.
+func readState(path string) (brokerState, error) {
+	state := brokerState{}
+
+	contents, err := os.ReadFile(path) //nolint:gosec // the path is configured by the broker's owner
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+
+	if err != nil {
+		return state, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	err = json.Unmarshal(contents, &state)
+	if err != nil {
+		return state, fmt.Errorf("failed to decode state file: %w", err)
+	}
+
+	return state, nil
+}
+
+// writeState replaces the state file at path, writing to a temporary file first so that a crash never leaves a
+// partially written state behind.
+func writeState(path string, state brokerState) error {
+	contents, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state into json: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+
+	defer func() { _ = os.Remove(temp.Name()) }()
+
+	_, err = temp.Write(contents)
+	closeErr := temp.Close()
+
+	err = errors.Join(err, closeErr)
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	err = os.Rename(temp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	return nil
+}
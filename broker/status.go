@@ -0,0 +1,207 @@
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRoutineNotFound occurs when the broker is asked to act on a routine it has not been given.
+var ErrRoutineNotFound = errors.New("broker does not have a routine with that name")
+
+// ErrFetcherPanicked occurs when a routine's fetcher panics, the broker recovers and reports the panic as an error.
+var ErrFetcherPanicked = errors.New("fetcher panicked")
+
+// ErrRoutinePaused occurs when a paused routine is asked to fetch.
+var ErrRoutinePaused = errors.New("routine is paused")
+
+// RoutineStatus describes the state of a routine managed by the broker.
+type RoutineStatus struct {
+	Name     string `json:"name"`
+	PollRate string `json:"pollRate"`
+	Paused   bool   `json:"paused"`
+	// LastFetch is the time the routine's last run started, nil when it has not run.
+	LastFetch *time.Time `json:"lastFetch,omitempty"`
+	// LastError describes the errors encountered fetching or pushing during the routine's last run.
+	LastError string `json:"lastError,omitempty"`
+	// LastPush is the time the routine's data was last pushed, nil when it has not been pushed.
+	LastPush *time.Time `json:"lastPush,omitempty"`
+	// LastPayload is the payload the routine last pushed, empty when its last push removed its app.
+	LastPayload json.RawMessage `json:"lastPayload,omitempty"`
+	Devices     []string        `json:"devices"`
+}
+
+// routineStatus is the state of a scheduled routine reported by the broker, it is safe for concurrent use.
+type routineStatus struct {
+	mu          sync.Mutex
+	paused      bool
+	lastFetch   time.Time
+	lastErr     error
+	lastPush    time.Time
+	lastPayload []byte
+}
+
+func (s *routineStatus) isPaused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.paused
+}
+
+func (s *routineStatus) setPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paused = paused
+}
+
+// recordRun records the outcome of a run that started at started.
+func (s *routineStatus) recordRun(started time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastFetch = started
+	s.lastErr = err
+}
+
+// recordPush records payload as the routine's last pushed payload.
+func (s *routineStatus) recordPush(payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastPush = time.Now()
+	s.lastPayload = payload
+}
+
+// status reports the routine's status.
+func (b *HTTPBroker) status(scheduled *scheduledRoutine) RoutineStatus {
+	state := &scheduled.status
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	status := RoutineStatus{
+		Name:     scheduled.routine.GetName(),
+		PollRate: pollRateOf(scheduled.routine).String(),
+		Paused:   state.paused,
+		Devices:  []string{},
+	}
+
+	if !state.lastFetch.IsZero() {
+		lastFetch := state.lastFetch
+		status.LastFetch = &lastFetch
+	}
+
+	if state.lastErr != nil {
+		status.LastError = state.lastErr.Error()
+	}
+
+	if !state.lastPush.IsZero() {
+		lastPush := state.lastPush
+		status.LastPush = &lastPush
+		status.LastPayload = state.lastPayload
+	}
+
+	for _, dvc := range b.devicesDisplaying(scheduled.routine) {
+		status.Devices = append(status.Devices, dvc.name)
+	}
+
+	return status
+}
+
+// Routines reports the status of every routine managed by the broker.
+func (b *HTTPBroker) Routines() []RoutineStatus {
+	statuses := make([]RoutineStatus, len(b.scheduled))
+
+	for i, scheduled := range b.scheduled {
+		statuses[i] = b.status(scheduled)
+	}
+
+	return statuses
+}
+
+// Routine reports the status of the routine named name.
+func (b *HTTPBroker) Routine(name string) (RoutineStatus, error) {
+	scheduled, err := b.scheduledNamed(name)
+	if err != nil {
+		return RoutineStatus{}, err
+	}
+
+	return b.status(scheduled), nil
+}
+
+// Wake runs the routine named name as soon as possible, fetching and pushing its data without waiting for its next
+// poll. Its following poll is measured from the end of the woken run. Wakes requested before the routine has started
+// its woken run are coalesced into a single run. It is safe to call from any goroutine, such as an http handler.
+func (b *HTTPBroker) Wake(name string) error {
+	scheduled, err := b.scheduledNamed(name)
+	if err != nil {
+		return err
+	}
+
+	if scheduled.status.isPaused() {
+		return fmt.Errorf("%w: %v", ErrRoutinePaused, name)
+	}
+
+	signal(scheduled.wake)
+
+	return nil
+}
+
+// Push pushes the last data of the routine named name to every device that displays it as soon as possible, even
+// when the data is unchanged or the routine has not requested a push. Paused routines can still be pushed.
+func (b *HTTPBroker) Push(name string) error {
+	scheduled, err := b.scheduledNamed(name)
+	if err != nil {
+		return err
+	}
+
+	signal(scheduled.push)
+
+	return nil
+}
+
+// Pause stops the routine named name from fetching and pushing until it is resumed.
+func (b *HTTPBroker) Pause(name string) error {
+	scheduled, err := b.scheduledNamed(name)
+	if err != nil {
+		return err
+	}
+
+	scheduled.status.setPaused(true)
+
+	return nil
+}
+
+// Resume restarts the paused routine named name, running it straight away.
+func (b *HTTPBroker) Resume(name string) error {
+	scheduled, err := b.scheduledNamed(name)
+	if err != nil {
+		return err
+	}
+
+	scheduled.status.setPaused(false)
+	signal(scheduled.wake)
+
+	return nil
+}
+
+func (b *HTTPBroker) scheduledNamed(name string) (*scheduledRoutine, error) {
+	for _, scheduled := range b.scheduled {
+		if scheduled.routine.GetName() == name {
+			return scheduled, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %v", ErrRoutineNotFound, name)
+}
+
+// signal queues a run on a buffered channel without blocking, a run that is already queued absorbs the signal.
+func signal(queue chan struct{}) {
+	select {
+	case queue <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,70 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/t-monaghan/altar/utils"
+)
+
+// contextTransport binds every request made through it to ctx, allowing the broker to cancel requests made by
+// fetchers regardless of the context they were created with.
+type contextTransport struct {
+	ctx  context.Context //nolint:containedctx // the transport exists to carry the broker's context into requests
+	base http.RoundTripper
+}
+
+// RoundTrip performs the request, cancelling it if either the request's or the transport's context is done.
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(t.ctx, cancel)
+
+	release := func() {
+		stop()
+		cancel()
+	}
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		release()
+
+		return nil, fmt.Errorf("failed to perform request bound to broker context: %w", err)
+	}
+
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+
+	return resp, nil
+}
+
+// releasingBody releases the request's context once the response body has been closed.
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close closes the response body and releases the context bound to its request.
+func (r *releasingBody) Close() error {
+	defer r.release()
+
+	err := r.ReadCloser.Close()
+	if err != nil {
+		return fmt.Errorf("%w: %w", utils.ErrClosingResponseBody, err)
+	}
+
+	return nil
+}
+
+// clientWithContext returns a copy of client whose requests are cancelled when ctx is done.
+func clientWithContext(ctx context.Context, client *http.Client) *http.Client {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	bound := *client
+	bound.Transport = &contextTransport{ctx: ctx, base: base}
+
+	return &bound
+}
@@ -0,0 +1,66 @@
+// Package discovery finds Awtrix devices on the local network.
+//
+// Devices are found by browsing for the Awtrix DNS-SD service over mDNS, or by probing the stats endpoint of every
+// host in a subnet.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/t-monaghan/altar/broker"
+)
+
+// Device is an Awtrix device found on the local network.
+type Device struct {
+	// Name is the device's configured name, or its mDNS instance name when it advertises none.
+	Name string
+	// Hostname is the device's mDNS hostname, it is empty for probed devices.
+	Hostname string
+	// Address is the base URL of the device's HTTP API.
+	Address string
+	// Version is the device's firmware version, when it is known.
+	Version string
+	// UID is the device's unique identifier, when it is known.
+	UID string
+}
+
+// Matches reports whether name identifies the device by its name, hostname or uid, ignoring case.
+func (d Device) Matches(name string) bool {
+	name = strings.TrimSuffix(name, ".local")
+
+	return strings.EqualFold(d.Name, name) ||
+		(d.Hostname != "" && strings.EqualFold(strings.TrimSuffix(d.Hostname, ".local"), name)) ||
+		(d.UID != "" && strings.EqualFold(d.UID, name))
+}
+
+// ErrDeviceNotFound occurs when no Awtrix device on the local network matches a name.
+var ErrDeviceNotFound = errors.New("no awtrix device found with the name")
+
+// Resolver returns a function that browses the local network for the Awtrix device identified by name, returning its
+// address.
+func Resolver(name string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		devices, err := Browse(ctx, DefaultBrowseDuration)
+		if err != nil {
+			return "", fmt.Errorf("failed to browse for %v: %w", name, err)
+		}
+
+		for _, device := range devices {
+			if device.Matches(name) {
+				return device.Address, nil
+			}
+		}
+
+		return "", fmt.Errorf("%w: %v", ErrDeviceNotFound, name)
+	}
+}
+
+// Bind returns a broker device bound to the Awtrix device identified by name. The broker resolves the device's
+// address when it starts and again whenever the device goes offline, so it follows the device across DHCP address
+// changes.
+func Bind(name string) broker.Device {
+	return broker.Device{Name: name, Resolve: Resolver(name)}
+}
@@ -0,0 +1,144 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/t-monaghan/altar/utils"
+)
+
+// appendRecord appends a resource record to an mDNS message, the record's owner is compressed with a pointer when
+// pointer is not zero.
+func appendRecord(message []byte, owner string, pointer uint16, recordType uint16, data []byte) []byte {
+	if pointer != 0 {
+		message = binary.BigEndian.AppendUint16(message, 0xC000|pointer)
+	} else {
+		message = appendName(message, owner)
+	}
+
+	message = binary.BigEndian.AppendUint16(message, recordType)
+	message = binary.BigEndian.AppendUint16(message, classIN)
+	message = binary.BigEndian.AppendUint32(message, 120)
+	message = binary.BigEndian.AppendUint16(message, uint16(len(data)))
+
+	return append(message, data...)
+}
+
+func awtrixResponse(t *testing.T) []byte {
+	t.Helper()
+
+	const instance = "awtrix_3a9f1c._awtrix._tcp.local."
+
+	const hostname = "awtrix_3a9f1c.local."
+
+	message := make([]byte, headerLength)
+	binary.BigEndian.PutUint16(message[6:], 2)  // two answers
+	binary.BigEndian.PutUint16(message[10:], 3) // three additional records
+
+	serviceOffset := uint16(len(message))
+	message = appendRecord(message, ServiceName, 0, typePTR, appendName(nil, instance))
+
+	srv := binary.BigEndian.AppendUint16(nil, 0)
+	srv = binary.BigEndian.AppendUint16(srv, 0)
+	srv = binary.BigEndian.AppendUint16(srv, 80)
+	message = appendRecord(message, instance, 0, typeSRV, appendName(srv, hostname))
+
+	text := []byte{}
+	for _, entry := range []string{"name=Office", "version=0.98", "id=3a9f1c"} {
+		text = append(text, byte(len(entry)))
+		text = append(text, entry...)
+	}
+
+	message = appendRecord(message, instance, 0, typeTXT, text)
+	message = appendRecord(message, hostname, 0, typeA, []byte{192, 168, 0, 42})
+
+	// a PTR owned by another name, compressed against the awtrix service's name, must be ignored
+	return appendRecord(message, "", serviceOffset+1+uint16(len("_awtrix")),
+		typePTR, appendName(nil, "printer._tcp.local."))
+}
+
+func Test_BrowseParsesAwtrixResponses(t *testing.T) {
+	t.Parallel()
+
+	records := newRecordSet()
+
+	err := records.parse(awtrixResponse(t))
+	if err != nil {
+		t.Fatalf("should not throw error parsing mDNS response\n\treceived error: %v", err)
+	}
+
+	devices := records.devices()
+	if len(devices) != 1 {
+		t.Fatalf("expected one device to be discovered\n\treceived: %+v", devices)
+	}
+
+	expected := Device{
+		Name:     "Office",
+		Hostname: "awtrix_3a9f1c.local",
+		Address:  "http://192.168.0.42",
+		Version:  "0.98",
+		UID:      "3a9f1c",
+	}
+	if devices[0] != expected {
+		t.Fatalf("discovered incorrect device\n\texpected: %+v\n\treceived: %+v", expected, devices[0])
+	}
+
+	for _, name := range []string{"office", "awtrix_3a9f1c", "awtrix_3a9f1c.local", "3A9F1C"} {
+		if !devices[0].Matches(name) {
+			t.Errorf("device should match the name %v", name)
+		}
+	}
+}
+
+func Test_BrowseRejectsMalformedResponses(t *testing.T) {
+	t.Parallel()
+
+	response := awtrixResponse(t)
+
+	for _, message := range [][]byte{
+		response[:headerLength-1],
+		response[:len(response)-3],
+		append(response[:headerLength:headerLength], 0xC0, headerLength), // a pointer to itself
+	} {
+		if err := newRecordSet().parse(message); err == nil {
+			t.Errorf("should throw error parsing malformed message: %v", message)
+		}
+	}
+}
+
+func Test_ProbeFindsAwtrixDevices(t *testing.T) {
+	t.Parallel()
+
+	client := utils.MockClient(func(request *http.Request) (*http.Response, error) {
+		body := ""
+
+		switch request.URL.Host {
+		case "10.0.0.2":
+			body = `{"version":"0.98","uid":"3a9f1c","uptime":120}`
+		case "10.0.0.3":
+			body = `{"status":"not an awtrix"}`
+		default:
+			return nil, http.ErrServerClosed
+		}
+
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(body))}, nil
+	})
+
+	devices, err := Probe(t.Context(), client, "10.0.0.0/28")
+	if err != nil {
+		t.Fatalf("should not throw error probing subnet\n\treceived error: %v", err)
+	}
+
+	expected := Device{Name: "3a9f1c", Address: "http://10.0.0.2", Version: "0.98", UID: "3a9f1c"}
+	if len(devices) != 1 || devices[0] != expected {
+		t.Fatalf("probe found incorrect devices\n\texpected: %+v\n\treceived: %+v", expected, devices)
+	}
+
+	_, err = Probe(t.Context(), client, "10.0.0.0/8")
+	if err == nil {
+		t.Fatal("should throw error probing a subnet that is too large")
+	}
+}
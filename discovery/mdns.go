@@ -0,0 +1,320 @@
+package discovery
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServiceName is the DNS-SD service Awtrix devices advertise over mDNS.
+const ServiceName = "_awtrix._tcp.local."
+
+// DefaultBrowseDuration is the default time spent collecting mDNS responses.
+const DefaultBrowseDuration = 2 * time.Second
+
+const (
+	mdnsAddress     = "224.0.0.251:5353"
+	maxPacketSize   = 9000
+	maxPointerJumps = 16
+	headerLength    = 12
+	defaultHTTPPort = 80
+
+	typeA   uint16 = 1
+	typePTR uint16 = 12
+	typeTXT uint16 = 16
+	typeSRV uint16 = 33
+	classIN uint16 = 1
+)
+
+// ErrMalformedMessage occurs when an mDNS response cannot be parsed.
+var ErrMalformedMessage = errors.New("malformed mDNS message")
+
+// Browse queries the local network over mDNS for Awtrix devices, collecting responses for wait or until ctx is done.
+// A wait of zero uses DefaultBrowseDuration.
+func Browse(ctx context.Context, wait time.Duration) ([]Device, error) {
+	if wait <= 0 {
+		wait = DefaultBrowseDuration
+	}
+
+	group, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve mDNS address: %w", err)
+	}
+
+	// querying from an ephemeral port asks responders for a unicast reply, so no multicast group has to be joined
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mDNS socket: %w", err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	deadline := time.Now().Add(wait)
+
+	err = conn.SetReadDeadline(deadline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set mDNS read deadline: %w", err)
+	}
+
+	_, err = conn.WriteTo(browseQuery(), group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send mDNS query: %w", err)
+	}
+
+	records := newRecordSet()
+	buffer := make([]byte, maxPacketSize)
+
+	for {
+		length, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+
+			return nil, fmt.Errorf("failed to read mDNS response: %w", err)
+		}
+
+		// a malformed response from one responder should not hide the others
+		_ = records.parse(buffer[:length])
+	}
+
+	return records.devices(), nil
+}
+
+// browseQuery builds a DNS-SD query for the Awtrix service's instances.
+func browseQuery() []byte {
+	message := make([]byte, headerLength)
+	binary.BigEndian.PutUint16(message[4:], 1) // one question
+
+	message = appendName(message, ServiceName)
+	message = binary.BigEndian.AppendUint16(message, typePTR)
+
+	return binary.BigEndian.AppendUint16(message, classIN)
+}
+
+func appendName(message []byte, name string) []byte {
+	for label := range strings.SplitSeq(strings.TrimSuffix(name, "."), ".") {
+		message = append(message, byte(len(label)))
+		message = append(message, label...)
+	}
+
+	return append(message, 0)
+}
+
+type service struct {
+	target string
+	port   uint16
+}
+
+// recordSet accumulates the records of every mDNS response received while browsing.
+type recordSet struct {
+	instances []string
+	services  map[string]service
+	texts     map[string]map[string]string
+	addresses map[string]net.IP
+}
+
+func newRecordSet() *recordSet {
+	return &recordSet{
+		services:  map[string]service{},
+		texts:     map[string]map[string]string{},
+		addresses: map[string]net.IP{},
+	}
+}
+
+// parse records the answers and additional records of an mDNS response.
+func (r *recordSet) parse(message []byte) error {
+	if len(message) < headerLength {
+		return ErrMalformedMessage
+	}
+
+	questions := int(binary.BigEndian.Uint16(message[4:]))
+	records := int(binary.BigEndian.Uint16(message[6:])) + int(binary.BigEndian.Uint16(message[8:])) +
+		int(binary.BigEndian.Uint16(message[10:]))
+	offset := headerLength
+
+	for range questions {
+		_, next, err := readName(message, offset)
+		if err != nil {
+			return err
+		}
+
+		offset = next + 4 //nolint:mnd // the question's type and class
+	}
+
+	for range records {
+		next, err := r.parseRecord(message, offset)
+		if err != nil {
+			return err
+		}
+
+		offset = next
+	}
+
+	return nil
+}
+
+func (r *recordSet) parseRecord(message []byte, offset int) (int, error) {
+	owner, offset, err := readName(message, offset)
+	if err != nil {
+		return 0, err
+	}
+
+	const fixedLength = 10 // type, class, ttl and data length
+	if offset+fixedLength > len(message) {
+		return 0, ErrMalformedMessage
+	}
+
+	recordType := binary.BigEndian.Uint16(message[offset:])
+	dataLength := int(binary.BigEndian.Uint16(message[offset+8:]))
+	start := offset + fixedLength
+	end := start + dataLength
+
+	if end > len(message) {
+		return 0, ErrMalformedMessage
+	}
+
+	data := message[start:end]
+
+	switch recordType {
+	case typePTR:
+		instance, _, err := readName(message, start)
+		if err != nil {
+			return 0, err
+		}
+
+		known := func(candidate string) bool { return strings.EqualFold(candidate, instance) }
+		if strings.EqualFold(owner, ServiceName) && !slices.ContainsFunc(r.instances, known) {
+			r.instances = append(r.instances, instance)
+		}
+	case typeSRV:
+		const targetOffset = 6 // priority, weight and port
+		if len(data) < targetOffset {
+			return 0, ErrMalformedMessage
+		}
+
+		target, _, err := readName(message, start+targetOffset)
+		if err != nil {
+			return 0, err
+		}
+
+		r.services[strings.ToLower(owner)] = service{target: target, port: binary.BigEndian.Uint16(data[4:])}
+	case typeTXT:
+		r.texts[strings.ToLower(owner)] = parseText(data)
+	case typeA:
+		if len(data) == net.IPv4len {
+			// the message's buffer is reused for the next response
+			r.addresses[strings.ToLower(owner)] = net.IP(slices.Clone(data))
+		}
+	}
+
+	return end, nil
+}
+
+// devices returns each advertised instance whose address is known.
+func (r *recordSet) devices() []Device {
+	devices := make([]Device, 0, len(r.instances))
+
+	for _, instance := range r.instances {
+		srv, ok := r.services[strings.ToLower(instance)]
+		if !ok {
+			continue
+		}
+
+		ip, ok := r.addresses[strings.ToLower(srv.target)]
+		if !ok {
+			continue
+		}
+
+		host := ip.String()
+		if srv.port != defaultHTTPPort {
+			host = net.JoinHostPort(host, strconv.Itoa(int(srv.port)))
+		}
+
+		text := r.texts[strings.ToLower(instance)]
+
+		name := text["name"]
+		if name == "" {
+			name, _, _ = strings.Cut(instance, ".")
+		}
+
+		devices = append(devices, Device{
+			Name:     name,
+			Hostname: strings.TrimSuffix(srv.target, "."),
+			Address:  "http://" + host,
+			Version:  text["version"],
+			UID:      text["id"],
+		})
+	}
+
+	return devices
+}
+
+// readName reads a possibly compressed domain name at offset, returning it and the offset following it.
+func readName(message []byte, offset int) (string, int, error) {
+	labels := []string{}
+	next := -1
+
+	for jumps := 0; ; {
+		if offset >= len(message) {
+			return "", 0, ErrMalformedMessage
+		}
+
+		length := int(message[offset])
+
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+
+			return strings.Join(labels, ".") + ".", next, nil
+		case length&0xC0 == 0xC0:
+			if offset+1 >= len(message) || jumps >= maxPointerJumps {
+				return "", 0, ErrMalformedMessage
+			}
+
+			if next < 0 {
+				next = offset + 2 //nolint:mnd // a pointer is two bytes long
+			}
+
+			offset = int(binary.BigEndian.Uint16(message[offset:]) & 0x3FFF)
+			jumps++
+		default:
+			if offset+1+length > len(message) {
+				return "", 0, ErrMalformedMessage
+			}
+
+			labels = append(labels, string(message[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
+
+// parseText parses the key=value strings of a TXT record.
+func parseText(data []byte) map[string]string {
+	text := map[string]string{}
+
+	for len(data) > 0 {
+		length := int(data[0])
+		if 1+length > len(data) {
+			break
+		}
+
+		key, value, _ := strings.Cut(string(data[1:1+length]), "=")
+		text[strings.ToLower(key)] = value
+		data = data[1+length:]
+	}
+
+	return text
+}
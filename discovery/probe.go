@@ -0,0 +1,117 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// DefaultProbeTimeout is the default time given to each host to answer a probe.
+const DefaultProbeTimeout = 2 * time.Second
+
+const (
+	probeConcurrency  = 32
+	maxProbedHostBits = 16
+)
+
+// ErrSubnetTooLarge occurs when a subnet has too many hosts to probe.
+var ErrSubnetTooLarge = errors.New("subnet has too many hosts to probe")
+
+// errNotAwtrix occurs when a host answers a probe without identifying as an Awtrix device.
+var errNotAwtrix = errors.New("host is not an awtrix device")
+
+// Probe finds Awtrix devices by requesting the stats of every host in subnet, given in CIDR notation such as
+// "192.168.0.0/24". The stats do not include a device's configured name, so probed devices are named by their uid.
+func Probe(ctx context.Context, client *http.Client, subnet string) ([]Device, error) {
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse subnet: %w", err)
+	}
+
+	prefix = prefix.Masked()
+	if prefix.Addr().BitLen()-prefix.Bits() > maxProbedHostBits {
+		return nil, fmt.Errorf("%w: %v", ErrSubnetTooLarge, subnet)
+	}
+
+	hosts := make(chan netip.Addr)
+	found := make(chan Device)
+
+	var probes sync.WaitGroup
+
+	for range probeConcurrency {
+		probes.Add(1)
+
+		go func() {
+			defer probes.Done()
+
+			for host := range hosts {
+				device, err := probeHost(ctx, client, host)
+				if err == nil {
+					found <- device
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(hosts)
+
+		for host := prefix.Addr(); prefix.Contains(host); host = host.Next() {
+			select {
+			case hosts <- host:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		probes.Wait()
+		close(found)
+	}()
+
+	devices := []Device{}
+	for device := range found {
+		devices = append(devices, device)
+	}
+
+	if ctx.Err() != nil {
+		return devices, fmt.Errorf("probe of %v was interrupted: %w", subnet, ctx.Err())
+	}
+
+	return devices, nil
+}
+
+func probeHost(ctx context.Context, client *http.Client, host netip.Addr) (Device, error) {
+	probeCtx, cancel := context.WithTimeout(ctx, DefaultProbeTimeout)
+	defer cancel()
+
+	hostname := host.String()
+	if host.Is6() {
+		hostname = "[" + hostname + "]"
+	}
+
+	device := &awtrix.HTTPClient{BaseURL: "http://" + hostname, HTTP: client}
+
+	stats, err := device.Stats(probeCtx)
+	if err != nil {
+		return Device{}, fmt.Errorf("failed to probe %v: %w", host, err)
+	}
+
+	if stats.UID == "" || stats.Version == "" {
+		return Device{}, fmt.Errorf("%w: %v", errNotAwtrix, host)
+	}
+
+	return Device{
+		Name:    stats.UID,
+		Address: device.BaseURL,
+		Version: stats.Version,
+		UID:     stats.UID,
+	}, nil
+}
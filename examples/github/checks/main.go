@@ -2,58 +2,54 @@
 package checks
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
-	"sync"
 
+	"github.com/t-monaghan/altar/inbox"
 	"github.com/t-monaghan/altar/notifier"
 )
 
-//nolint:gochecknoglobals
-var (
-	checksChannel      chan Progress
-	once               sync.Once
-	channelInitialized bool
-)
+// NewInbox creates the inbox that passes progress from the inbox's handler to Fetcher. Each progress update describes
+// the whole check run, so only the latest is kept.
+func NewInbox() *inbox.Inbox[Progress] {
+	return inbox.New[Progress](1, inbox.CoalesceLatest)
+}
+
+// Fetcher returns a fetcher that receives progress from the handler of progressInbox and prepares it to be posted by
+// altar's broker.
+func Fetcher(progressInbox *inbox.Inbox[Progress]) func(*notifier.Notifier, *http.Client) error {
+	return func(ntfr *notifier.Notifier, _ *http.Client) error {
+		info, ok := progressInbox.Take()
+		if !ok {
+			ntfr.PushOnNextCall = false
+
+			return nil
+		}
+
+		slog.Debug("githubchecks fetcher received message", "msg", info)
 
-const channelBufferSize = 5
+		showProgress(ntfr, info)
 
-func initChannel() {
-	once.Do(func() {
-		checksChannel = make(chan Progress, channelBufferSize)
-		channelInitialized = true
-	})
+		return nil
+	}
 }
 
-// Fetcher receives data from the handler and prepares it to be posted by altar's broker.
-func Fetcher(ntfr *notifier.Notifier, _ *http.Client) error {
+// showProgress prepares the notifier to show the progress of the check run.
+func showProgress(ntfr *notifier.Notifier, info Progress) {
 	falseVal := false
 	trueVal := true
 
-	if !channelInitialized {
-		initChannel()
-	}
-
-	progressOutOfAHundred := 0
+	progressOutOfAHundred := int(float64(info.CompletedActions) / float64(info.TotalActions) * 100) //nolint:mnd
 
-	var info Progress
+	// a failure is held on the device until it is dismissed, so it is cleared once no checks are failing
+	heldFailure := ntfr.Data.Hold != nil && *ntfr.Data.Hold && ntfr.Data.BlinkText != nil
+	ntfr.DismissOnNextCall = heldFailure && len(info.FailedActions) == 0
 
-	select {
-	case info = <-checksChannel:
-		slog.Debug("githubchecks fetcher received message", "msg", info)
-	default:
+	if !ntfr.DismissOnNextCall && ntfr.Data.Progress != nil && progressOutOfAHundred == *ntfr.Data.Progress {
 		ntfr.PushOnNextCall = false
 
-		return nil
-	}
-
-	progressOutOfAHundred = int(float64(info.CompletedActions) / float64(info.TotalActions) * 100) //nolint:mnd
-
-	if ntfr.Data.Progress != nil && progressOutOfAHundred == *ntfr.Data.Progress {
-		return nil
+		return
 	}
 
 	ntfr.Data.Progress = &progressOutOfAHundred
@@ -76,20 +72,21 @@ func Fetcher(ntfr *notifier.Notifier, _ *http.Client) error {
 			ntfr.Data.Text = fmt.Sprintf("%v failing", len(info.FailedActions))
 		}
 
-		return nil
+		return
 	}
 
+	ntfr.Data.BlinkText = nil
+	ntfr.Data.Hold = &falseVal
+
 	if progressOutOfAHundred == 100 { //nolint:mnd
 		ntfr.Data.Hold = &trueVal
 		ntfr.Data.Text = "passing"
 		ntfr.Data.Color = []int{0, 190, 0}
 
-		return nil
+		return
 	}
 
 	ntfr.Data.Text = fmt.Sprintf("%v/%v jobs", info.CompletedActions, info.TotalActions)
-
-	return nil
 }
 
 // Progress represents the progress of required checks for a given PR.
@@ -98,49 +95,3 @@ type Progress struct {
 	CompletedActions int      `json:"completedActions"`
 	FailedActions    []string `json:"failedActions"`
 }
-
-// Handler receives data from the gh-altar tool and passes it onto Fetcher.
-func Handler(rsp http.ResponseWriter, req *http.Request) {
-	if !channelInitialized {
-		initChannel()
-	}
-
-	body, err := io.ReadAll(req.Body)
-	if err != nil {
-		slog.Error("github checks handler failed to read body", "error", err)
-		rsp.WriteHeader(http.StatusBadRequest)
-
-		return
-	}
-
-	slog.Debug("github checks handler received message", "body", string(body))
-
-	var checks Progress
-	err = json.Unmarshal(body, &checks)
-
-	if err != nil {
-		slog.Error("github checks handler failed to unmarshal request", "error", err)
-		rsp.WriteHeader(http.StatusBadRequest)
-
-		return
-	}
-
-	slog.Debug("github checks handler posted message to channel", "msg", checks)
-
-	select {
-	case checksChannel <- checks:
-	default:
-		slog.Warn("github checks channel is full, dropping message")
-	}
-
-	rsp.WriteHeader(http.StatusOK)
-}
-
-// Reset clears the state of the channel used to communicate between the api handler and the altar fetcher.
-func Reset() {
-	if channelInitialized {
-		for len(checksChannel) > 0 {
-			<-checksChannel
-		}
-	}
-}
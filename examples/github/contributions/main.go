@@ -3,75 +3,44 @@
 package contributions
 
 import (
-	"encoding/json"
-	"io"
 	"log/slog"
 	"net/http"
 	"slices"
-	"sync"
 	"time"
 
 	"github.com/t-monaghan/altar/application"
+	"github.com/t-monaghan/altar/inbox"
 )
 
-//nolint:gochecknoglobals
-var (
-	contributionsChannel chan []int
-	once                 sync.Once
-	channelInitialized   bool
-)
-
-// Handler receives contributions data from `gh altar contributions` and passes it to Fetcher.
-func Handler(rsp http.ResponseWriter, req *http.Request) {
-	if !channelInitialized {
-		initChannel()
-	}
-
-	body, err := io.ReadAll(req.Body)
-	if err != nil {
-		slog.Error("github contributions handler failed to read body", "error", err)
-		rsp.WriteHeader(http.StatusBadRequest)
-
-		return
-	}
-
-	var contributions []int
-
-	err = json.Unmarshal(body, &contributions)
-	if err != nil {
-		slog.Error("github contributions handler failed to unmarshal request", "error", err)
-		rsp.WriteHeader(http.StatusBadRequest)
+// NewInbox creates the inbox that passes contribution counts from the inbox's handler to Fetcher. Each count covers
+// the whole contribution graph, so only the latest is kept.
+func NewInbox() *inbox.Inbox[[]int] {
+	return inbox.New[[]int](1, inbox.CoalesceLatest)
+}
 
-		return
-	}
+// Fetcher returns a fetcher that receives contribution counts from the handler of countInbox and prepares them to be
+// posted by altar's broker.
+func Fetcher(countInbox *inbox.Inbox[[]int]) func(*application.Application, *http.Client) error {
+	return func(app *application.Application, _ *http.Client) error {
+		rawCount, ok := countInbox.Take()
+		if !ok {
+			app.PushOnNextCall = false
 
-	select {
-	case contributionsChannel <- contributions:
-	default:
-		slog.Warn("github contributions channel is full, dropping message")
-	}
+			return nil
+		}
 
-	rsp.WriteHeader(http.StatusOK)
-}
+		slog.Debug("contributions fetcher received contributions count", "length-of-count", len(rawCount))
 
-// Fetcher receives data from the handler and prepares it to be posted by altar's broker.
-func Fetcher(app *application.Application, _ *http.Client) error {
-	if !channelInitialized {
-		initChannel()
-	}
+		app.PushOnNextCall = true
 
-	var rawCount []int
-	select {
-	case rawCount = <-contributionsChannel:
-		slog.Debug("contributions fetcher received contributions count", "length-of-count", len(rawCount))
-	default:
-		app.PushOnNextCall = false
+		showContributions(app, rawCount)
 
 		return nil
 	}
+}
 
-	app.PushOnNextCall = true
-
+// showContributions draws the contribution graph for rawCount.
+func showContributions(app *application.Application, rawCount []int) {
 	graph := contributionGraphsDrawInstruction(rawCount)
 
 	firstWeekOfMonth := firstWeekOfMonthDrawInstruction()
@@ -80,8 +49,6 @@ func Fetcher(app *application.Application, _ *http.Client) error {
 		{Bitmap: &graph},
 		{Bitmap: &firstWeekOfMonth},
 	}
-
-	return nil
 }
 
 const black = 0x000000
@@ -196,21 +163,3 @@ func daysWillFitOnDisplay() int {
 
 	return displayDays
 }
-
-const channelBufferSize = 5
-
-func initChannel() {
-	once.Do(func() {
-		contributionsChannel = make(chan []int, channelBufferSize)
-		channelInitialized = true
-	})
-}
-
-// Reset clears the state of the channel used to communicate between the api handler and the altar fetcher.
-func Reset() {
-	if channelInitialized {
-		for len(contributionsChannel) > 0 {
-			<-contributionsChannel
-		}
-	}
-}
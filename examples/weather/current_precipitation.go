@@ -10,8 +10,8 @@ import (
 	"os"
 )
 
-func currentPrecipitation(client *http.Client) (float64, error) {
-	req, err := http.NewRequestWithContext(context.Background(),
+func currentPrecipitation(ctx context.Context, client *http.Client) (float64, error) {
+	req, err := http.NewRequestWithContext(ctx,
 		http.MethodGet, "https://api.open-meteo.com/v1/forecast", nil)
 	if err != nil {
 		return 0, fmt.Errorf("error creating request for current precipitation: %w", err)
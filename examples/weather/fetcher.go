@@ -2,6 +2,7 @@
 package weather
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -15,8 +16,8 @@ const blueHex = "#3396FF"
 const whiteHex = "#FFFFFF"
 
 // Fetcher displays information about precipitation in Melbourne.
-func Fetcher(app *application.Application, client *http.Client) error {
-	precip, err := currentPrecipitation(client)
+func Fetcher(ctx context.Context, app *application.Application, client *http.Client) error {
+	precip, err := currentPrecipitation(ctx, client)
 	if err != nil {
 		return fmt.Errorf("error querying current precipitation: %w", err)
 	}
@@ -35,7 +36,7 @@ func Fetcher(app *application.Application, client *http.Client) error {
 	app.Data.Overlay = ""
 	app.GlobalConfig.Overlay = awtrix.Clear
 
-	nextRain, foundRain, err := weeklyRainForecast(client)
+	nextRain, foundRain, err := weeklyRainForecast(ctx, client)
 	if err != nil {
 		return err
 	}
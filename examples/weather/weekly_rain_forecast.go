@@ -23,8 +23,8 @@ type HourlyForecast struct {
 // ErrEmptyResponse describes when the weather api returns an empty body.
 var ErrEmptyResponse = errors.New("did not receive a response body from weather api")
 
-func weeklyRainForecast(client *http.Client) (HourlyForecast, bool, error) {
-	req, err := http.NewRequestWithContext(context.Background(),
+func weeklyRainForecast(ctx context.Context, client *http.Client) (HourlyForecast, bool, error) {
+	req, err := http.NewRequestWithContext(ctx,
 		http.MethodGet, "https://api.open-meteo.com/v1/forecast", nil)
 	if err != nil {
 		return HourlyForecast{}, false, fmt.Errorf("error creating request for weekly rain forecast: %w", err)
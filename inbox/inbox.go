@@ -0,0 +1,182 @@
+// Package inbox provides a typed inbox for handing data from outside of a broker, such as an http handler, to a
+// routine's fetcher.
+//
+// Each inbox is an ordinary value owned by the program that creates it, so several routines can each have their own
+// inbox without sharing package state.
+package inbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// OverflowPolicy defines what an inbox does with an item put into it while it is full.
+type OverflowPolicy int
+
+const (
+	// DropOldest discards the oldest item waiting in the inbox to make room for the new item.
+	DropOldest OverflowPolicy = iota
+	// DropNewest discards the new item, keeping the items already waiting.
+	DropNewest
+	// CoalesceLatest keeps only the most recent item, replacing any item waiting in the inbox. It suits items that
+	// each describe the whole of a state, where only the latest matters, so a replaced item is superseded by the new
+	// item rather than dropped and is not counted by Dropped.
+	CoalesceLatest
+)
+
+// DefaultCapacity is the capacity of an inbox created with a capacity below one.
+const DefaultCapacity = 1
+
+// maxBodyBytes bounds the size of a request body accepted by an inbox's handler.
+const maxBodyBytes = 1 << 20
+
+// Inbox is a bounded, typed queue of items waiting to be taken by a routine's fetcher. It is safe for concurrent use.
+type Inbox[T any] struct {
+	// OnPut is called each time an item is put in the inbox, such as to wake the routine that takes from it with
+	// broker.Wake. It is called without the inbox's lock held, and must be set before the inbox is used.
+	OnPut    func()
+	mu       sync.Mutex
+	items    []T
+	capacity int
+	policy   OverflowPolicy
+	dropped  uint64
+}
+
+// New creates an inbox holding up to capacity items, applying policy to items put into it while it is full. An inbox
+// with the CoalesceLatest policy holds a single item regardless of its capacity.
+func New[T any](capacity int, policy OverflowPolicy) *Inbox[T] {
+	if capacity < 1 || policy == CoalesceLatest {
+		capacity = DefaultCapacity
+	}
+
+	return &Inbox[T]{
+		items:    make([]T, 0, capacity),
+		capacity: capacity,
+		policy:   policy,
+	}
+}
+
+// Put adds item to the inbox, returning false when the inbox was full and the item was dropped because of its
+// DropNewest policy.
+func (i *Inbox[T]) Put(item T) bool {
+	accepted := i.put(item)
+
+	if accepted && i.OnPut != nil {
+		i.OnPut()
+	}
+
+	return accepted
+}
+
+func (i *Inbox[T]) put(item T) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if len(i.items) < i.capacity {
+		i.items = append(i.items, item)
+
+		return true
+	}
+
+	switch i.policy {
+	case DropNewest:
+		i.dropped++
+
+		return false
+	case CoalesceLatest:
+		i.items[len(i.items)-1] = item
+	case DropOldest:
+		i.dropped++
+		i.items = append(i.items[1:], item)
+	}
+
+	return true
+}
+
+// Take removes and returns the oldest item in the inbox, returning false when the inbox is empty.
+func (i *Inbox[T]) Take() (T, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var item T
+
+	if len(i.items) == 0 {
+		return item, false
+	}
+
+	item = i.items[0]
+	i.items = i.items[1:]
+
+	return item, true
+}
+
+// Drain removes and returns every item in the inbox, oldest first.
+func (i *Inbox[T]) Drain() []T {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	items := i.items
+	i.items = make([]T, 0, i.capacity)
+
+	return items
+}
+
+// Len returns the number of items waiting in the inbox.
+func (i *Inbox[T]) Len() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return len(i.items)
+}
+
+// Dropped returns the number of items the inbox has discarded because it was full, either the new item under the
+// DropNewest policy or the oldest item under the DropOldest policy.
+func (i *Inbox[T]) Dropped() uint64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.dropped
+}
+
+// Handler returns an http handler that decodes each request's json body into an item and puts it in the inbox. It
+// responds with 400 when the body is not a valid item, and 503 when the item is dropped because the inbox is full.
+func (i *Inbox[T]) Handler() http.HandlerFunc {
+	return func(rsp http.ResponseWriter, req *http.Request) {
+		item, err := decode[T](req.Body)
+		if err != nil {
+			slog.Error("inbox handler failed to decode request", "path", req.URL.Path, "error", err)
+			http.Error(rsp, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if !i.Put(item) {
+			slog.Warn("inbox is full, dropping message", "path", req.URL.Path)
+			http.Error(rsp, "inbox is full", http.StatusServiceUnavailable)
+
+			return
+		}
+
+		rsp.WriteHeader(http.StatusOK)
+	}
+}
+
+func decode[T any](body io.Reader) (T, error) {
+	var item T
+
+	contents, err := io.ReadAll(io.LimitReader(body, maxBodyBytes))
+	if err != nil {
+		return item, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	err = json.Unmarshal(contents, &item)
+	if err != nil {
+		return item, fmt.Errorf("failed to unmarshal request body: %w", err)
+	}
+
+	return item, nil
+}
@@ -0,0 +1,145 @@
+package inbox_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/t-monaghan/altar/inbox"
+)
+
+func Test_InboxOverflowPolicies(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		policy   inbox.OverflowPolicy
+		accepted []bool
+		expected []int
+		dropped  uint64
+	}{
+		{inbox.DropOldest, []bool{true, true, true, true}, []int{3, 4}, 2},
+		{inbox.DropNewest, []bool{true, true, false, false}, []int{1, 2}, 2},
+		{inbox.CoalesceLatest, []bool{true, true, true, true}, []int{4}, 0},
+	}
+
+	for _, testCase := range cases {
+		items := inbox.New[int](2, testCase.policy)
+
+		for i, item := range []int{1, 2, 3, 4} {
+			if accepted := items.Put(item); accepted != testCase.accepted[i] {
+				t.Fatalf("policy %v did not accept item %v as expected\n\texpected: %v\n\treceived: %v",
+					testCase.policy, item, testCase.accepted[i], accepted)
+			}
+		}
+
+		if dropped := items.Dropped(); dropped != testCase.dropped {
+			t.Fatalf("policy %v counted incorrect number of dropped items\n\texpected: %v\n\treceived: %v",
+				testCase.policy, testCase.dropped, dropped)
+		}
+
+		if drained := items.Drain(); !slices.Equal(drained, testCase.expected) {
+			t.Fatalf("policy %v kept incorrect items\n\texpected: %v\n\treceived: %v",
+				testCase.policy, testCase.expected, drained)
+		}
+
+		if _, ok := items.Take(); ok {
+			t.Fatalf("policy %v inbox should be empty once drained", testCase.policy)
+		}
+	}
+}
+
+func Test_InboxTakesOldestFirst(t *testing.T) {
+	t.Parallel()
+
+	items := inbox.New[string](3, inbox.DropOldest)
+	items.Put("first")
+	items.Put("second")
+
+	for _, expected := range []string{"first", "second"} {
+		item, ok := items.Take()
+		if !ok || item != expected {
+			t.Fatalf("inbox returned incorrect item\n\texpected: %v\n\treceived: %v", expected, item)
+		}
+	}
+
+	if items.Len() != 0 || items.Dropped() != 0 {
+		t.Fatalf("inbox should be empty without dropping items: %v waiting, %v dropped", items.Len(), items.Dropped())
+	}
+}
+
+func Test_InboxIsSafeForConcurrentUse(t *testing.T) {
+	t.Parallel()
+
+	items := inbox.New[int](100, inbox.DropNewest)
+
+	var putting sync.WaitGroup
+
+	for i := range 100 {
+		putting.Add(1)
+
+		go func() {
+			defer putting.Done()
+
+			items.Put(i)
+		}()
+	}
+
+	putting.Wait()
+
+	if waiting := items.Len(); waiting != 100 {
+		t.Fatalf("inbox lost items\n\texpected: 100\n\treceived: %v", waiting)
+	}
+}
+
+func Test_InboxCallsOnPut(t *testing.T) {
+	t.Parallel()
+
+	puts := 0
+	items := inbox.New[int](1, inbox.DropNewest)
+	items.OnPut = func() { puts++ }
+
+	items.Put(1)
+	items.Put(2)
+
+	if puts != 1 {
+		t.Fatalf("inbox should only call OnPut for accepted items\n\texpected: 1\n\treceived: %v", puts)
+	}
+}
+
+type progress struct {
+	Completed int `json:"completed"`
+}
+
+func Test_InboxHandler(t *testing.T) {
+	t.Parallel()
+
+	items := inbox.New[progress](1, inbox.DropNewest)
+	handler := items.Handler()
+
+	cases := []struct {
+		body   string
+		status int
+	}{
+		{`{"completed":3}`, http.StatusOK},
+		{`{"completed":4}`, http.StatusServiceUnavailable},
+		{`not json`, http.StatusBadRequest},
+	}
+
+	for _, testCase := range cases {
+		recorder := httptest.NewRecorder()
+		handler(recorder, httptest.NewRequest(http.MethodPost, "/api/progress", strings.NewReader(testCase.body)))
+
+		if recorder.Code != testCase.status {
+			t.Fatalf("handler responded to %v with incorrect status\n\texpected: %v\n\treceived: %v",
+				testCase.body, testCase.status, recorder.Code)
+		}
+	}
+
+	item, ok := items.Take()
+	if !ok || item.Completed != 3 {
+		t.Fatalf("handler put incorrect item in inbox: %+v", item)
+	}
+}
@@ -0,0 +1,167 @@
+// Package indicator provides functionality to drive the Awtrix device's corner indicators from altar brokers.
+package indicator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/t-monaghan/altar/utils"
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// Position identifies one of the three indicators on the right edge of the Awtrix display.
+type Position int
+
+const (
+	// Top is the indicator in the upper right corner of the display.
+	Top Position = 1
+	// Middle is the indicator on the middle of the right edge of the display.
+	Middle Position = 2
+	// Bottom is the indicator in the lower right corner of the display.
+	Bottom Position = 3
+)
+
+// ErrInvalidPosition occurs when an indicator routine targets an indicator the Awtrix device does not have.
+var ErrInvalidPosition = errors.New("indicator position must be between 1 and 3")
+
+// Valid reports whether the position is one of the indicators present on the Awtrix device.
+func (p Position) Valid() bool {
+	return p >= Top && p <= Bottom
+}
+
+// State is altar's representation of what an Awtrix indicator displays.
+type State struct {
+	Color []int `json:"color,omitempty"` // RGB color values [R,G,B], black turns the indicator off
+	Blink *int  `json:"blink,omitempty"` // milliseconds between blinks
+	Fade  *int  `json:"fade,omitempty"`  // milliseconds taken to fade in and out
+}
+
+// Indicator is altar's approach of managing the data retrieval and storage required to drive an Awtrix indicator.
+type Indicator struct {
+	Name                string
+	Position            Position
+	fetcher             func(context.Context, *Indicator, *http.Client) error
+	Data                State
+	GlobalConfig        awtrix.Config
+	PollRate            time.Duration
+	HTTPClient          *http.Client
+	PushOnNextCall      bool
+	ForcePushOnNextCall bool
+	lastPolled          time.Time
+}
+
+// NewIndicator instantiates a new altar indicator routine for the indicator at position.
+func NewIndicator(name string, position Position, fetcher func(*Indicator, *http.Client) error) Indicator {
+	return NewIndicatorWithContext(name, position,
+		func(_ context.Context, indctr *Indicator, client *http.Client) error {
+			return fetcher(indctr, client)
+		})
+}
+
+// NewIndicatorWithContext instantiates a new altar indicator routine whose fetcher receives a context from the
+// broker.
+//
+// The context carries the broker's deadline for each fetch and is cancelled when the broker shuts down.
+func NewIndicatorWithContext(
+	name string,
+	position Position,
+	fetcher func(context.Context, *Indicator, *http.Client) error,
+) Indicator {
+	return Indicator{
+		Name:           name,
+		Position:       position,
+		fetcher:        fetcher,
+		Data:           State{},
+		GlobalConfig:   awtrix.Config{},
+		PollRate:       utils.DefaultPollRate,
+		PushOnNextCall: false,
+	}
+}
+
+// Fetch controls the fetching for an indicator.
+func (i *Indicator) Fetch(ctx context.Context, client *http.Client) error {
+	if !i.ShouldFetch() {
+		slog.Debug("skipping indicator fetch", "indicator", i.Name,
+			"seconds-since-last-fetch", time.Since(i.lastPolled).Seconds(), "poll-rate-seconds", i.PollRate.Seconds())
+
+		return nil
+	}
+
+	slog.Debug("fetching for indicator", "indicator", i.Name,
+		"seconds-since-last-fetch", time.Since(i.lastPolled).Seconds(), "poll-rate-seconds", i.PollRate.Seconds())
+
+	i.lastPolled = time.Now()
+	i.PushOnNextCall = true
+	i.ForcePushOnNextCall = false
+
+	return i.fetcher(ctx, i, client)
+}
+
+// SetColour lights the indicator in a solid colour.
+func (i *Indicator) SetColour(red, green, blue uint8) {
+	i.Data = State{Color: []int{int(red), int(green), int(blue)}}
+}
+
+// TurnOff hides the indicator.
+func (i *Indicator) TurnOff() {
+	i.Data = State{Color: []int{0, 0, 0}}
+}
+
+// GetName returns the indicator's name.
+func (i *Indicator) GetName() string {
+	return i.Name
+}
+
+// GetPollRate returns the indicator's poll rate.
+func (i *Indicator) GetPollRate() time.Duration {
+	return i.PollRate
+}
+
+// GetData returns the indicator's data, this is the payload sent to the awtrix device.
+func (i *Indicator) GetData() any {
+	return i.Data
+}
+
+// Endpoint returns the Awtrix endpoint of the indicator at the routine's position.
+func (i *Indicator) Endpoint() (awtrix.Endpoint, error) {
+	if !i.Position.Valid() {
+		return awtrix.Endpoint{}, fmt.Errorf("%w: %v", ErrInvalidPosition, i.Position)
+	}
+
+	return awtrix.IndicatorEndpoint(int(i.Position)), nil
+}
+
+// GetGlobalConfig returns the global config this indicator wishes to manipulate.
+func (i *Indicator) GetGlobalConfig() awtrix.Config {
+	return i.GlobalConfig
+}
+
+// Wake makes the indicator's next fetch run regardless of its poll rate.
+func (i *Indicator) Wake() {
+	i.lastPolled = time.Time{}
+}
+
+// ShouldFetch signals whether this indicator should have it's fetch method run.
+func (i *Indicator) ShouldFetch() bool {
+	return time.Since(i.lastPolled) > i.PollRate
+}
+
+// ShouldPushToAwtrix signals whether a broker should push this indicator's data to the awtrix device.
+func (i *Indicator) ShouldPushToAwtrix() bool {
+	return i.PushOnNextCall
+}
+
+// ShouldForcePush signals whether a broker should push this indicator's data even when it is unchanged.
+func (i *Indicator) ShouldForcePush() bool {
+	return i.ForcePushOnNextCall
+}
+
+// SetPollRateByRateLimit is a helper function that sets the indicator's poll rate
+// when given the count of requests per duration.
+func (i *Indicator) SetPollRateByRateLimit(requests uint32, duration time.Duration) {
+	i.PollRate = duration / time.Duration(requests)
+}
@@ -0,0 +1,109 @@
+package indicator_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/t-monaghan/altar/indicator"
+)
+
+func Test_IndicatorEndpoint(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		position indicator.Position
+		expected string
+		err      error
+	}{
+		{indicator.Top, "/api/indicator1", nil},
+		{indicator.Bottom, "/api/indicator3", nil},
+		{0, "", indicator.ErrInvalidPosition},
+		{4, "", indicator.ErrInvalidPosition},
+	}
+
+	for _, testCase := range cases {
+		indctr := indicator.NewIndicator("ci", testCase.position, func(*indicator.Indicator, *http.Client) error {
+			return nil
+		})
+
+		endpoint, err := indctr.Endpoint()
+		if !errors.Is(err, testCase.err) {
+			t.Fatalf("did not throw expected error\n\texpected: %v\n\treceived: %v", testCase.err, err)
+		}
+
+		if endpoint.Path != testCase.expected {
+			t.Fatalf("indicator returned incorrect endpoint\n\texpected: %v\n\treceived: %v",
+				testCase.expected, endpoint.Path)
+		}
+
+		if err == nil && !endpoint.Retained {
+			t.Fatalf("indicator endpoint should be retained, the device loses its indicators when it reboots")
+		}
+	}
+}
+
+func Test_IndicatorFetchMarksPush(t *testing.T) {
+	t.Parallel()
+
+	indctr := indicator.NewIndicator("ci", indicator.Top, func(i *indicator.Indicator, _ *http.Client) error {
+		i.SetColour(255, 0, 0)
+
+		return nil
+	})
+	indctr.ForcePushOnNextCall = true
+
+	err := indctr.Fetch(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("should not throw error fetching\n\treceived error: %v", err)
+	}
+
+	if !indctr.ShouldPushToAwtrix() {
+		t.Fatal("indicator should push its data after fetching")
+	}
+
+	if indctr.ShouldForcePush() {
+		t.Fatal("indicator should clear its force flag when fetching")
+	}
+}
+
+func Test_IndicatorPayloads(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		description string
+		update      func(*indicator.Indicator)
+		expected    string
+	}{
+		{"set colour", func(i *indicator.Indicator) { i.SetColour(255, 128, 0) }, `{"color":[255,128,0]}`},
+		{"turn off", func(i *indicator.Indicator) { i.TurnOff() }, `{"color":[0,0,0]}`},
+		{
+			"turn off a blinking indicator",
+			func(i *indicator.Indicator) {
+				blink := 500
+				i.Data = indicator.State{Color: []int{255, 0, 0}, Blink: &blink}
+				i.TurnOff()
+			},
+			`{"color":[0,0,0]}`,
+		},
+	}
+
+	for _, testCase := range cases {
+		indctr := indicator.NewIndicator("ci", indicator.Middle, func(*indicator.Indicator, *http.Client) error {
+			return nil
+		})
+
+		testCase.update(&indctr)
+
+		payload, err := json.Marshal(indctr.GetData())
+		if err != nil {
+			t.Fatalf("should not throw error marshalling payload\n\treceived error: %v", err)
+		}
+
+		if string(payload) != testCase.expected {
+			t.Fatalf("%v produced incorrect payload\n\texpected: %v\n\treceived: %v",
+				testCase.description, testCase.expected, string(payload))
+		}
+	}
+}
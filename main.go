@@ -19,13 +19,16 @@ import (
 )
 
 func main() {
-	githubChecks := notifier.NewNotifier("github checks", checks.Fetcher)
-	weather := application.NewApplication("rain forecast", weather.Fetcher)
-	githubContributions := application.NewApplication("github contributions", contributions.Fetcher)
+	checksInbox := checks.NewInbox()
+	contributionsInbox := contributions.NewInbox()
+
+	githubChecks := notifier.NewNotifier("github checks", checks.Fetcher(checksInbox))
+	weather := application.NewApplicationWithContext("rain forecast", weather.Fetcher)
+	githubContributions := application.NewApplication("github contributions", contributions.Fetcher(contributionsInbox))
 
 	handlers := map[string]func(http.ResponseWriter, *http.Request){
-		"/api/pipeline-watcher": checks.Handler,
-		"/api/contributions":    contributions.Handler,
+		"/api/pipeline-watcher": checksInbox.Handler(),
+		"/api/contributions":    contributionsInbox.Handler(),
 		"/api/buttons":          buttons.Handler,
 	}
 
@@ -47,6 +50,21 @@ func main() {
 		os.Exit(1)
 	}
 
+	// admin commands are only accepted with the token when one is configured
+	if token := os.Getenv("ALTAR_ADMIN_TOKEN"); token != "" {
+		brkr.AdminAuth = broker.BearerToken(token)
+	}
+
+	brkr.StateFile = os.Getenv("ALTAR_STATE_FILE")
+	brkr.DropCounters = map[string]broker.DropCounter{
+		"/api/pipeline-watcher": checksInbox,
+		"/api/contributions":    contributionsInbox,
+	}
+
+	// data from gh-altar is shown as soon as it arrives, rather than on the routines' next poll
+	checksInbox.OnPut = func() { _ = brkr.Wake(githubChecks.Name) }
+	contributionsInbox.OnPut = func() { _ = brkr.Wake(githubContributions.Name) }
+
 	brkr.Start()
 }
 
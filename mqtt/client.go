@@ -0,0 +1,513 @@
+// Package mqtt provides an awtrix.Client that talks to Awtrix devices over MQTT.
+//
+// Awtrix 3 subscribes to topics under its configured MQTT prefix for custom apps, notifications, settings and
+// indicators, and publishes its stats and button presses. The Client speaks MQTT 3.1.1 to the same MQTT broker as the
+// device, publishing at QoS 0, and can be given to the altar broker as a device's Client.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// DefaultKeepAlive is the default interval within which the client pings the MQTT broker.
+const DefaultKeepAlive = 30 * time.Second
+
+// DefaultStatsMaxAge is the default age after which stats published by the device are considered stale.
+const DefaultStatsMaxAge = 30 * time.Second
+
+const (
+	subscribePacketID = 1
+	clientIDBytes     = 6
+	pingsPerKeepAlive = 2
+)
+
+// Button is one of the Awtrix device's physical buttons.
+type Button string
+
+const (
+	// ButtonLeft is the device's left button.
+	ButtonLeft Button = "buttonLeft"
+	// ButtonSelect is the device's middle button.
+	ButtonSelect Button = "buttonSelect"
+	// ButtonRight is the device's right button.
+	ButtonRight Button = "buttonRight"
+)
+
+// ButtonEvent is a press or release of one of the device's buttons.
+type ButtonEvent struct {
+	Button  Button
+	Pressed bool
+}
+
+// ErrConnectionRefused occurs when the MQTT broker refuses the client's connection.
+var ErrConnectionRefused = errors.New("MQTT broker refused the connection")
+
+// ErrSubscriptionRefused occurs when the MQTT broker refuses the client's subscription to the device's topics.
+var ErrSubscriptionRefused = errors.New("MQTT broker refused the subscription")
+
+// ErrStatsUnavailable occurs when the device has not published its stats within the client's StatsMaxAge.
+var ErrStatsUnavailable = errors.New("awtrix device has not published its stats")
+
+// ErrClientClosed occurs when a closed client is used.
+var ErrClientClosed = errors.New("MQTT client is closed")
+
+var _ awtrix.Client = (*Client)(nil)
+
+// Client publishes to and subscribes to an Awtrix device's topics on an MQTT broker. The client connects when it is
+// first used and reconnects whenever its connection is lost.
+type Client struct {
+	// Prefix is the MQTT prefix configured on the Awtrix device.
+	Prefix string
+	// ClientID identifies the client to the MQTT broker, a random ID is used when it is empty.
+	ClientID string
+	// Username and Password authenticate the client with the MQTT broker, they are omitted when empty.
+	Username string
+	Password string
+	// KeepAlive is the interval within which the client pings the MQTT broker.
+	KeepAlive time.Duration
+	// StatsMaxAge is the age after which the device's last published stats are considered stale. The device's health
+	// is judged from its stats, so it should exceed the stats interval configured on the device.
+	StatsMaxAge time.Duration
+	// OnButton is called with each press and release of the device's buttons. It is called from the client's
+	// connection, so it must not block.
+	OnButton func(ButtonEvent)
+	address  string
+
+	connMu sync.Mutex
+	conn   *connection
+	closed bool
+
+	statsMu sync.Mutex
+	stats   awtrix.Stats
+	statsAt time.Time
+	// statsUpdated is closed and replaced each time the device publishes its stats.
+	statsUpdated chan struct{}
+}
+
+// connection is a single connection to the MQTT broker.
+type connection struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewClient creates a client for the Awtrix device with the MQTT prefix prefix, on the MQTT broker at address.
+func NewClient(address string, prefix string) *Client {
+	return &Client{
+		Prefix:       strings.TrimSuffix(prefix, "/"),
+		KeepAlive:    DefaultKeepAlive,
+		StatsMaxAge:  DefaultStatsMaxAge,
+		address:      address,
+		statsUpdated: make(chan struct{}),
+	}
+}
+
+// Connect connects to the MQTT broker and subscribes to the device's stats and button topics, if the client is not
+// already connected.
+func (c *Client) Connect(ctx context.Context) error {
+	_, err := c.connection(ctx)
+
+	return err
+}
+
+// Close disconnects from the MQTT broker, the client cannot be used once it is closed.
+func (c *Client) Close() error {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	c.closed = true
+
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.write(packet{kind: disconnectPacket}, time.Time{})
+	c.conn.close()
+	c.conn = nil
+
+	return err
+}
+
+// Send publishes payload to the device's topic for endpoint.
+func (c *Client) Send(ctx context.Context, endpoint awtrix.Endpoint, payload []byte) error {
+	conn, err := c.connection(ctx)
+	if err != nil {
+		return err
+	}
+
+	deadline, _ := ctx.Deadline()
+
+	return conn.write(newPublish(c.Topic(endpoint), payload), deadline)
+}
+
+// Stats returns the stats the device last published. When they are older than StatsMaxAge, Stats waits for the
+// device to publish fresh stats until StatsMaxAge passes or ctx is done.
+func (c *Client) Stats(ctx context.Context) (awtrix.Stats, error) {
+	_, err := c.connection(ctx)
+	if err != nil {
+		return awtrix.Stats{}, err
+	}
+
+	maxAge := c.StatsMaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultStatsMaxAge
+	}
+
+	timeout := time.NewTimer(maxAge)
+	defer timeout.Stop()
+
+	for {
+		c.statsMu.Lock()
+		stats, statsAt, updated := c.stats, c.statsAt, c.statsUpdated
+		c.statsMu.Unlock()
+
+		if !statsAt.IsZero() && time.Since(statsAt) <= maxAge {
+			return stats, nil
+		}
+
+		select {
+		case <-updated:
+		case <-timeout.C:
+			return awtrix.Stats{}, ErrStatsUnavailable
+		case <-ctx.Done():
+			return awtrix.Stats{}, fmt.Errorf("stopped waiting for awtrix stats: %w", ctx.Err())
+		}
+	}
+}
+
+// SetSettings publishes cfg to the device's settings topic.
+func (c *Client) SetSettings(ctx context.Context, cfg awtrix.Config) error {
+	return awtrix.SendJSON(ctx, c, awtrix.SettingsEndpoint(), cfg) //nolint:wrapcheck // wrapped by SendJSON
+}
+
+// PushApp publishes app to the topic of the custom app named name.
+func (c *Client) PushApp(ctx context.Context, name string, app any) error {
+	return awtrix.SendJSON(ctx, c, awtrix.CustomAppEndpoint(name), app) //nolint:wrapcheck // wrapped by SendJSON
+}
+
+// Notify publishes notification to the device's notify topic.
+func (c *Client) Notify(ctx context.Context, notification any) error {
+	return awtrix.SendJSON(ctx, c, awtrix.NotifyEndpoint(), notification) //nolint:wrapcheck // wrapped by SendJSON
+}
+
+// DismissNotification publishes to the device's notification dismissal topic.
+func (c *Client) DismissNotification(ctx context.Context) error {
+	return c.Send(ctx, awtrix.DismissNotificationEndpoint(), nil)
+}
+
+// SetIndicator publishes state to the topic of the indicator numbered indicator, from 1 at the top.
+func (c *Client) SetIndicator(ctx context.Context, indicator int, state any) error {
+	//nolint:wrapcheck // wrapped by SendJSON
+	return awtrix.SendJSON(ctx, c, awtrix.IndicatorEndpoint(indicator), state)
+}
+
+// Reboot publishes to the device's reboot topic.
+func (c *Client) Reboot(ctx context.Context) error {
+	return c.Send(ctx, awtrix.RebootEndpoint(), nil)
+}
+
+// Topic returns the device's topic for an endpoint of its HTTP API, e.g. the custom app endpoint
+// "/api/custom?name=weather" is published to "<prefix>/custom/weather".
+func (c *Client) Topic(endpoint awtrix.Endpoint) string {
+	topic := c.Prefix + strings.TrimPrefix(endpoint.Path, "/api")
+	if name := endpoint.Query.Get("name"); name != "" {
+		topic += "/" + name
+	}
+
+	return topic
+}
+
+// connection returns the client's connection to the MQTT broker, connecting if it is not connected.
+func (c *Client) connection(ctx context.Context) (*connection, error) {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+
+	if c.conn != nil {
+		select {
+		case <-c.conn.done:
+		default:
+			return c.conn, nil
+		}
+	}
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.conn = conn
+
+	return conn, nil
+}
+
+func (c *Client) dial(ctx context.Context) (*connection, error) {
+	dialer := net.Dialer{}
+
+	netConn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+
+	conn := &connection{conn: netConn, done: make(chan struct{})}
+	reader := bufio.NewReader(netConn)
+
+	stop := context.AfterFunc(ctx, conn.close)
+	defer stop()
+
+	// a broker that accepts the connection but never answers must not hold the client's lock forever
+	deadline := time.Now().Add(c.keepAlive())
+	_ = netConn.SetReadDeadline(deadline)
+
+	err = c.handshake(conn, reader, deadline)
+	if err == nil {
+		err = netConn.SetDeadline(time.Time{})
+	}
+
+	if err != nil {
+		conn.close()
+
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to connect to MQTT broker: %w", ctx.Err())
+		}
+
+		return nil, err
+	}
+
+	go c.readLoop(conn, reader)
+	go c.pingLoop(conn)
+
+	return conn, nil
+}
+
+// handshake connects to the MQTT broker and subscribes to the device's stats and button topics.
+func (c *Client) handshake(conn *connection, reader *bufio.Reader, deadline time.Time) error {
+	err := conn.write(c.connectPacket(), deadline)
+	if err != nil {
+		return err
+	}
+
+	connack, err := readPacket(reader)
+	if err != nil {
+		return err
+	}
+
+	const connackLength = 2
+	if connack.kind != connackPacket || len(connack.body) != connackLength {
+		return fmt.Errorf("%w: expected CONNACK", ErrMalformedPacket)
+	}
+
+	if code := connack.body[1]; code != 0 {
+		return fmt.Errorf("%w: return code %v", ErrConnectionRefused, code)
+	}
+
+	subscribe := binary.BigEndian.AppendUint16(nil, subscribePacketID)
+	for _, filter := range []string{c.Prefix + "/stats", c.Prefix + "/stats/+"} {
+		subscribe = append(appendString(subscribe, filter), 0)
+	}
+
+	err = conn.write(packet{kind: subscribePacket, flags: subscribeFlags, body: subscribe}, deadline)
+	if err != nil {
+		return err
+	}
+
+	for {
+		received, err := readPacket(reader)
+		if err != nil {
+			return err
+		}
+
+		if received.kind != subackPacket {
+			c.handle(conn, received)
+
+			continue
+		}
+
+		for _, code := range received.body[min(packetIDLength, len(received.body)):] {
+			if code == subscribeFailure {
+				return ErrSubscriptionRefused
+			}
+		}
+
+		return nil
+	}
+}
+
+func (c *Client) connectPacket() packet {
+	flags := byte(cleanSessionFlag)
+	if c.Username != "" {
+		flags |= usernameFlag
+	}
+
+	if c.Password != "" {
+		flags |= passwordFlag
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, protocolLevel, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(min(c.keepAlive()/time.Second, math.MaxUint16)))
+	body = appendString(body, c.clientID())
+
+	if c.Username != "" {
+		body = appendString(body, c.Username)
+	}
+
+	if c.Password != "" {
+		body = appendString(body, c.Password)
+	}
+
+	return packet{kind: connectPacket, body: body}
+}
+
+func (c *Client) clientID() string {
+	if c.ClientID != "" {
+		return c.ClientID
+	}
+
+	random := make([]byte, clientIDBytes)
+	_, _ = rand.Read(random)
+
+	return "altar-" + hex.EncodeToString(random)
+}
+
+func (c *Client) keepAlive() time.Duration {
+	if c.KeepAlive <= 0 {
+		return DefaultKeepAlive
+	}
+
+	return c.KeepAlive
+}
+
+// readLoop handles the packets the MQTT broker sends until the connection is lost. The connection is considered lost
+// when nothing, not even a ping response, is received for one and a half keep alive intervals.
+func (c *Client) readLoop(conn *connection, reader *bufio.Reader) {
+	defer conn.close()
+
+	timeout := c.keepAlive() + c.keepAlive()/pingsPerKeepAlive
+
+	for {
+		_ = conn.conn.SetReadDeadline(time.Now().Add(timeout))
+
+		received, err := readPacket(reader)
+		if err != nil {
+			select {
+			case <-conn.done:
+			default:
+				slog.Warn("lost connection to MQTT broker", "error", err)
+			}
+
+			return
+		}
+
+		c.handle(conn, received)
+	}
+}
+
+func (c *Client) pingLoop(conn *connection) {
+	ticker := time.NewTicker(c.keepAlive() / pingsPerKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-conn.done:
+			return
+		case <-ticker.C:
+			err := conn.write(packet{kind: pingreqPacket}, time.Time{})
+			if err != nil {
+				conn.close()
+
+				return
+			}
+		}
+	}
+}
+
+// handle dispatches a packet received from the MQTT broker.
+func (c *Client) handle(conn *connection, received packet) {
+	if received.kind != publishPacket {
+		return
+	}
+
+	msg, err := parsePublish(received)
+	if err != nil {
+		slog.Debug("ignoring malformed MQTT message", "error", err)
+
+		return
+	}
+
+	if msg.qos == 1 {
+		_ = conn.write(packet{kind: pubackPacket, body: binary.BigEndian.AppendUint16(nil, msg.packetID)}, time.Time{})
+	}
+
+	switch subtopic, _ := strings.CutPrefix(msg.topic, c.Prefix+"/stats"); {
+	case subtopic == "":
+		c.receiveStats(msg.payload)
+	case strings.HasPrefix(subtopic, "/button") && c.OnButton != nil:
+		c.OnButton(ButtonEvent{
+			Button:  Button(strings.TrimPrefix(subtopic, "/")),
+			Pressed: strings.TrimSpace(string(msg.payload)) == "1",
+		})
+	}
+}
+
+func (c *Client) receiveStats(payload []byte) {
+	stats := awtrix.Stats{}
+
+	err := json.Unmarshal(payload, &stats)
+	if err != nil {
+		slog.Debug("ignoring malformed awtrix stats", "error", err)
+
+		return
+	}
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.stats = stats
+	c.statsAt = time.Now()
+	close(c.statsUpdated)
+	c.statsUpdated = make(chan struct{})
+}
+
+// write sends a packet, bounded by deadline when it is not zero.
+func (c *connection) write(p packet, deadline time.Time) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.SetWriteDeadline(deadline)
+
+	_, err := c.conn.Write(p.encode())
+	if err != nil {
+		c.close()
+
+		return fmt.Errorf("failed to write MQTT packet: %w", err)
+	}
+
+	return nil
+}
+
+func (c *connection) close() {
+	c.once.Do(func() {
+		close(c.done)
+		_ = c.conn.Close()
+	})
+}
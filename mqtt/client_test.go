@@ -0,0 +1,368 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/t-monaghan/altar/application"
+	"github.com/t-monaghan/altar/broker"
+	"github.com/t-monaghan/altar/utils"
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+const testPrefix = "awtrix_test"
+
+// testBroker is a minimal in-process MQTT broker, supporting the subset of MQTT 3.1.1 the client uses.
+type testBroker struct {
+	listener    net.Listener
+	mu          sync.Mutex
+	subscribers map[net.Conn][]string
+	published   chan message
+}
+
+func startTestBroker(t *testing.T) *testBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start test MQTT broker: %v", err)
+	}
+
+	mqttBroker := &testBroker{
+		listener:    listener,
+		subscribers: map[net.Conn][]string{},
+		published:   make(chan message, 10),
+	}
+
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			t.Cleanup(func() { _ = conn.Close() })
+
+			go mqttBroker.serve(conn)
+		}
+	}()
+
+	return mqttBroker
+}
+
+func (b *testBroker) address() string {
+	return b.listener.Addr().String()
+}
+
+func (b *testBroker) serve(conn net.Conn) {
+	defer func() {
+		b.mu.Lock()
+		delete(b.subscribers, conn)
+		b.mu.Unlock()
+
+		_ = conn.Close()
+	}()
+
+	reader := bufio.NewReader(conn)
+
+	for {
+		received, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+
+		switch received.kind {
+		case connectPacket:
+			_, _ = conn.Write(packet{kind: connackPacket, body: []byte{0, 0}}.encode())
+		case subscribePacket:
+			filters := []string{}
+			codes := []byte{}
+
+			for rest := received.body[packetIDLength:]; len(rest) > 0; rest = rest[1:] {
+				var filter string
+
+				filter, rest, err = readString(rest)
+				if err != nil || len(rest) == 0 {
+					return
+				}
+
+				filters = append(filters, filter)
+				codes = append(codes, 0)
+			}
+
+			b.mu.Lock()
+			b.subscribers[conn] = append(b.subscribers[conn], filters...)
+			b.mu.Unlock()
+
+			suback := append(received.body[:packetIDLength:packetIDLength], codes...)
+			_, _ = conn.Write(packet{kind: subackPacket, body: suback}.encode())
+		case publishPacket:
+			msg, err := parsePublish(received)
+			if err != nil {
+				return
+			}
+
+			b.published <- msg
+		case pingreqPacket:
+			_, _ = conn.Write(packet{kind: pingrespPacket}.encode())
+		case disconnectPacket:
+			return
+		}
+	}
+}
+
+// publish sends a message to every subscriber whose filter matches topic.
+func (b *testBroker) publish(topic string, payload string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for conn, filters := range b.subscribers {
+		for _, filter := range filters {
+			if topicMatches(filter, topic) {
+				_, _ = conn.Write(newPublish(topic, []byte(payload)).encode())
+
+				break
+			}
+		}
+	}
+}
+
+// disconnectAll drops every client's connection.
+func (b *testBroker) disconnectAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for conn := range b.subscribers {
+		_ = conn.Close()
+	}
+}
+
+func topicMatches(filter string, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+
+	return len(filterLevels) == len(topicLevels)
+}
+
+func (b *testBroker) expectPublish(t *testing.T, topic string, payload string) {
+	t.Helper()
+
+	select {
+	case msg := <-b.published:
+		if msg.topic != topic || string(msg.payload) != payload {
+			t.Fatalf("client published incorrect message\n\texpected: %v %v\n\treceived: %v %v",
+				topic, payload, msg.topic, string(msg.payload))
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatalf("timed out waiting for client to publish to %v", topic)
+	}
+}
+
+func Test_ClientPublishesToDeviceTopics(t *testing.T) {
+	t.Parallel()
+
+	mqttBroker := startTestBroker(t)
+	client := NewClient(mqttBroker.address(), testPrefix+"/")
+
+	t.Cleanup(func() { _ = client.Close() })
+
+	cases := []struct {
+		endpoint awtrix.Endpoint
+		topic    string
+	}{
+		{awtrix.Endpoint{Path: "/api/custom", Query: url.Values{"name": {"weather"}}}, testPrefix + "/custom/weather"},
+		{awtrix.Endpoint{Path: "/api/notify"}, testPrefix + "/notify"},
+		{awtrix.Endpoint{Path: "/api/settings"}, testPrefix + "/settings"},
+		{awtrix.Endpoint{Path: "/api/indicator1"}, testPrefix + "/indicator1"},
+	}
+
+	for _, testCase := range cases {
+		err := client.Send(t.Context(), testCase.endpoint, []byte(`{"text":"hi"}`))
+		if err != nil {
+			t.Fatalf("should not throw error publishing\n\treceived error: %v", err)
+		}
+
+		mqttBroker.expectPublish(t, testCase.topic, `{"text":"hi"}`)
+	}
+}
+
+func Test_ClientReceivesStatsAndButtons(t *testing.T) {
+	t.Parallel()
+
+	mqttBroker := startTestBroker(t)
+	client := NewClient(mqttBroker.address(), testPrefix)
+	buttons := make(chan ButtonEvent, 1)
+	client.OnButton = func(event ButtonEvent) { buttons <- event }
+
+	t.Cleanup(func() { _ = client.Close() })
+
+	err := client.Connect(t.Context())
+	if err != nil {
+		t.Fatalf("should not throw error connecting\n\treceived error: %v", err)
+	}
+
+	mqttBroker.publish(testPrefix+"/stats", `{"uptime":321,"version":"0.98"}`)
+
+	stats, err := client.Stats(t.Context())
+	if err != nil {
+		t.Fatalf("should not throw error waiting for stats\n\treceived error: %v", err)
+	}
+
+	if stats.Uptime != 321 || stats.Version != "0.98" {
+		t.Fatalf("client received incorrect stats: %+v", stats)
+	}
+
+	mqttBroker.publish(testPrefix+"/stats/buttonLeft", "1")
+
+	select {
+	case event := <-buttons:
+		if expected := (ButtonEvent{Button: ButtonLeft, Pressed: true}); event != expected {
+			t.Fatalf("client received incorrect button event\n\texpected: %+v\n\treceived: %+v", expected, event)
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("timed out waiting for button event")
+	}
+}
+
+func Test_ClientReportsMissingStats(t *testing.T) {
+	t.Parallel()
+
+	mqttBroker := startTestBroker(t)
+	client := NewClient(mqttBroker.address(), testPrefix)
+	client.StatsMaxAge = 50 * time.Millisecond
+
+	t.Cleanup(func() { _ = client.Close() })
+
+	_, err := client.Stats(t.Context())
+	if !errors.Is(err, ErrStatsUnavailable) {
+		t.Fatalf("did not throw expected error\n\texpected: %v\n\treceived: %v", ErrStatsUnavailable, err)
+	}
+}
+
+func Test_ClientReconnects(t *testing.T) {
+	t.Parallel()
+
+	mqttBroker := startTestBroker(t)
+	client := NewClient(mqttBroker.address(), testPrefix)
+
+	t.Cleanup(func() { _ = client.Close() })
+
+	err := client.Connect(t.Context())
+	if err != nil {
+		t.Fatalf("should not throw error connecting\n\treceived error: %v", err)
+	}
+
+	client.connMu.Lock()
+	dropped := client.conn.done
+	client.connMu.Unlock()
+
+	mqttBroker.disconnectAll()
+
+	select {
+	case <-dropped:
+	case <-time.After(time.Second * 3):
+		t.Fatal("timed out waiting for client to notice its dropped connection")
+	}
+
+	err = client.Send(t.Context(), awtrix.Endpoint{Path: "/api/notify"}, []byte("{}"))
+	if err != nil {
+		t.Fatalf("should not throw error publishing after reconnecting\n\treceived error: %v", err)
+	}
+
+	mqttBroker.expectPublish(t, testPrefix+"/notify", "{}")
+}
+
+func Test_BrokerPushesOverMQTT(t *testing.T) {
+	t.Parallel()
+
+	mqttBroker := startTestBroker(t)
+	client := NewClient(mqttBroker.address(), testPrefix)
+
+	t.Cleanup(func() { _ = client.Close() })
+
+	app := application.NewApplication("weather", func(a *application.Application, _ *http.Client) error {
+		a.Data.Text = "sunny"
+
+		return nil
+	})
+
+	brkr, err := broker.NewMultiDeviceBroker(
+		[]broker.Device{{Name: "office", Client: client}},
+		[]utils.Routine{&app},
+		map[string]func(http.ResponseWriter, *http.Request){},
+	)
+	if err != nil {
+		t.Fatalf("should not throw error creating broker\n\treceived error: %v", err)
+	}
+
+	adminListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("should not throw error listening for admin server\n\treceived error: %v", err)
+	}
+
+	brkr.AdminListener = adminListener
+	brkr.DebugMode = true
+	brkr.HealthCheckInterval = -1
+
+	ctx, cancel := context.WithCancel(t.Context())
+	runErr := make(chan error, 1)
+
+	go func() { runErr <- brkr.Run(ctx) }()
+
+	select {
+	case msg := <-mqttBroker.published:
+		if msg.topic != testPrefix+"/custom/weather" || !strings.Contains(string(msg.payload), `"text":"sunny"`) {
+			t.Fatalf("broker published incorrect message: %v %v", msg.topic, string(msg.payload))
+		}
+	case <-time.After(time.Second * 3):
+		t.Fatal("timed out waiting for broker to publish over MQTT")
+	}
+
+	cancel()
+
+	if err := <-runErr; err != nil {
+		t.Fatalf("broker should shut down without error\n\treceived error: %v", err)
+	}
+}
+
+func Test_PacketLengthRoundTrips(t *testing.T) {
+	t.Parallel()
+
+	for _, length := range []int{0, 127, 128, 16383, 16384, 2097152} {
+		encoded := packet{kind: publishPacket, body: make([]byte, length)}.encode()
+
+		decoded, err := readPacket(bufio.NewReader(strings.NewReader(string(encoded))))
+		if err != nil || len(decoded.body) != length {
+			t.Fatalf("packet of length %v did not round trip: %v", length, err)
+		}
+	}
+
+	// a length with a fifth continuation byte is malformed
+	malformed := []byte{byte(publishPacket) << typeShift, 0xFF, 0xFF, 0xFF, 0xFF, 0x01}
+
+	_, err := readPacket(bufio.NewReader(strings.NewReader(string(malformed))))
+	if !errors.Is(err, ErrMalformedPacket) {
+		t.Fatalf("did not throw expected error\n\texpected: %v\n\treceived: %v", ErrMalformedPacket, err)
+	}
+
+}
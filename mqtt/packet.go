@@ -0,0 +1,164 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// packetType identifies an MQTT 3.1.1 control packet.
+type packetType byte
+
+const (
+	connectPacket    packetType = 1
+	connackPacket    packetType = 2
+	publishPacket    packetType = 3
+	pubackPacket     packetType = 4
+	subscribePacket  packetType = 8
+	subackPacket     packetType = 9
+	pingreqPacket    packetType = 12
+	pingrespPacket   packetType = 13
+	disconnectPacket packetType = 14
+)
+
+const (
+	protocolLevel    = 4
+	cleanSessionFlag = 0x02
+	passwordFlag     = 0x40
+	usernameFlag     = 0x80
+	subscribeFlags   = 0x02
+	subscribeFailure = 0x80
+	qosMask          = 0x06
+	typeShift        = 4
+	lengthContinues  = 0x80
+	lengthDigitBits  = 7
+	maxLengthDigits  = 4
+	packetIDLength   = 2
+)
+
+// ErrMalformedPacket occurs when an MQTT packet cannot be parsed.
+var ErrMalformedPacket = errors.New("malformed MQTT packet")
+
+// packet is a single MQTT control packet.
+type packet struct {
+	kind  packetType
+	flags byte
+	body  []byte
+}
+
+// readPacket reads the next control packet from r.
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, fmt.Errorf("failed to read MQTT packet: %w", err)
+	}
+
+	length := 0
+
+	for shift := 0; ; shift += lengthDigitBits {
+		if shift >= maxLengthDigits*lengthDigitBits {
+			return packet{}, ErrMalformedPacket
+		}
+
+		digit, err := r.ReadByte()
+		if err != nil {
+			return packet{}, fmt.Errorf("failed to read MQTT packet length: %w", err)
+		}
+
+		length |= int(digit&^lengthContinues) << shift
+
+		if digit&lengthContinues == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+
+	_, err = io.ReadFull(r, body)
+	if err != nil {
+		return packet{}, fmt.Errorf("failed to read MQTT packet body: %w", err)
+	}
+
+	return packet{kind: packetType(header >> typeShift), flags: header & 0x0F, body: body}, nil
+}
+
+// encode returns the packet's wire format.
+func (p packet) encode() []byte {
+	encoded := []byte{byte(p.kind)<<typeShift | p.flags}
+
+	length := len(p.body)
+	for {
+		digit := byte(length & (lengthContinues - 1))
+		length >>= lengthDigitBits
+
+		if length > 0 {
+			digit |= lengthContinues
+		}
+
+		encoded = append(encoded, digit)
+
+		if length == 0 {
+			break
+		}
+	}
+
+	return append(encoded, p.body...)
+}
+
+func appendString(body []byte, value string) []byte {
+	body = binary.BigEndian.AppendUint16(body, uint16(len(value))) //nolint:gosec // MQTT strings are length checked
+
+	return append(body, value...)
+}
+
+// readString reads a length-prefixed string from body, returning it and the rest of body.
+func readString(body []byte) (string, []byte, error) {
+	const lengthPrefix = 2
+	if len(body) < lengthPrefix {
+		return "", nil, ErrMalformedPacket
+	}
+
+	length := int(binary.BigEndian.Uint16(body))
+	if len(body) < lengthPrefix+length {
+		return "", nil, ErrMalformedPacket
+	}
+
+	return string(body[lengthPrefix : lengthPrefix+length]), body[lengthPrefix+length:], nil
+}
+
+// message is an application message carried by a PUBLISH packet.
+type message struct {
+	topic    string
+	payload  []byte
+	packetID uint16
+	qos      byte
+}
+
+func newPublish(topic string, payload []byte) packet {
+	return packet{kind: publishPacket, body: append(appendString(nil, topic), payload...)}
+}
+
+// parsePublish parses the message carried by a PUBLISH packet.
+func parsePublish(p packet) (message, error) {
+	topic, rest, err := readString(p.body)
+	if err != nil {
+		return message{}, err
+	}
+
+	msg := message{topic: topic, qos: (p.flags & qosMask) >> 1}
+
+	if msg.qos > 0 {
+		if len(rest) < packetIDLength {
+			return message{}, ErrMalformedPacket
+		}
+
+		msg.packetID = binary.BigEndian.Uint16(rest)
+		rest = rest[packetIDLength:]
+	}
+
+	msg.payload = rest
+
+	return msg, nil
+}
@@ -2,6 +2,7 @@
 package notifier
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"time"
@@ -13,17 +14,30 @@ import (
 // Notifier is altar's approach of managing the data retrieval and storage required of a custom Awtrix notifier.
 type Notifier struct {
 	Name           string
-	fetcher        func(*Notifier, *http.Client) error
+	fetcher        func(context.Context, *Notifier, *http.Client) error
 	Data           *NotificationData
 	GlobalConfig   awtrix.Config
 	PollRate       time.Duration
 	HTTPClient     *http.Client
 	PushOnNextCall bool
-	lastPolled     time.Time
+	// DismissOnNextCall dismisses the notification the device is showing, such as a held notification, before the
+	// next push. It is reset before each fetch.
+	DismissOnNextCall bool
+	lastPolled        time.Time
 }
 
 // NewNotifier instantiates a new altar notification routine.
 func NewNotifier(name string, fetcher func(*Notifier, *http.Client) error) Notifier {
+	return NewNotifierWithContext(name, func(_ context.Context, ntfr *Notifier, client *http.Client) error {
+		return fetcher(ntfr, client)
+	})
+}
+
+// NewNotifierWithContext instantiates a new altar notification routine whose fetcher receives a context from the
+// broker.
+//
+// The context carries the broker's deadline for each fetch and is cancelled when the broker shuts down.
+func NewNotifierWithContext(name string, fetcher func(context.Context, *Notifier, *http.Client) error) Notifier {
 	return Notifier{
 		Name:           name,
 		Data:           &NotificationData{},
@@ -35,7 +49,10 @@ func NewNotifier(name string, fetcher func(*Notifier, *http.Client) error) Notif
 }
 
 // Fetch controls the fetching for a notifier.
-func (n *Notifier) Fetch(client *http.Client) error {
+func (n *Notifier) Fetch(ctx context.Context, client *http.Client) error {
+	// a dismissal is only requested by the fetch before the broker's next push
+	n.DismissOnNextCall = false
+
 	if !n.ShouldFetch() {
 		slog.Debug("skipping notifier fetch", "notifier", n.Name,
 			"seconds-since-last-fetch", time.Since(n.lastPolled).Seconds(), "poll-rate-seconds", n.PollRate.Seconds())
@@ -48,7 +65,7 @@ func (n *Notifier) Fetch(client *http.Client) error {
 
 	n.lastPolled = time.Now()
 
-	return n.fetcher(n, client)
+	return n.fetcher(ctx, n, client)
 }
 
 // NotificationData is altar's presentation of a custom Awtrix notification.
@@ -103,11 +120,21 @@ func (n *Notifier) GetData() any {
 	return n.Data
 }
 
+// Endpoint returns the Awtrix endpoint notifications are sent to.
+func (n *Notifier) Endpoint() (awtrix.Endpoint, error) {
+	return awtrix.NotifyEndpoint(), nil
+}
+
 // GetGlobalConfig returns the global config this notifier wishes to manipulate.
 func (n *Notifier) GetGlobalConfig() awtrix.Config {
 	return n.GlobalConfig
 }
 
+// Wake makes the notifier's next fetch run regardless of its poll rate.
+func (n *Notifier) Wake() {
+	n.lastPolled = time.Time{}
+}
+
 // ShouldFetch signals whether this notifier should have it's fetch method run.
 func (n *Notifier) ShouldFetch() bool {
 	return time.Since(n.lastPolled) > n.PollRate
@@ -118,6 +145,11 @@ func (n *Notifier) ShouldPushToAwtrix() bool {
 	return n.PushOnNextCall
 }
 
+// ShouldDismiss signals whether a broker should dismiss the notification the device is showing.
+func (n *Notifier) ShouldDismiss() bool {
+	return n.DismissOnNextCall
+}
+
 // SetPollRateByRateLimit is a helper function that sets the notifiers's poll rate
 // when given the count of requests per duration.
 func (n *Notifier) SetPollRateByRateLimit(requests uint32, duration time.Duration) {
@@ -0,0 +1,238 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+// Priority orders the notifications waiting in a Queue, notifications with a higher priority are shown first.
+type Priority int
+
+const (
+	// PriorityLow notifications wait for every other notification.
+	PriorityLow Priority = iota - 1
+	// PriorityNormal is the priority of notifications queued without one.
+	PriorityNormal
+	// PriorityHigh notifications are shown before normal ones, but wait for the notification being shown.
+	PriorityHigh
+	// PriorityUrgent notifications interrupt any notification with a lower priority that is being shown.
+	PriorityUrgent
+)
+
+// DefaultQueuePollRate is the default rate a Queue is checked for a notification to show.
+const DefaultQueuePollRate = time.Second
+
+// DefaultNotificationDuration is how long a queue expects a notification without a duration to be shown for,
+// matching the default app time of an Awtrix device.
+const DefaultNotificationDuration = 7 * time.Second
+
+// DefaultHoldDuration is how long a queue expects a held notification to be shown for before it is dismissed.
+const DefaultHoldDuration = time.Minute
+
+// ErrRateLimited occurs when a notification is queued by a source that has exceeded the queue's rate limit.
+var ErrRateLimited = errors.New("notification source has exceeded its rate limit")
+
+// Notification is a notification waiting to be shown by a Queue.
+type Notification struct {
+	Data     NotificationData
+	Priority Priority
+	// Key deduplicates notifications, queuing a notification replaces a waiting notification with the same key.
+	// Notifications without a key are never deduplicated.
+	Key string
+	// Source identifies who queued the notification, the queue's rate limit applies to each source separately.
+	Source string
+	// Expires is the time a notification that has not been shown is dropped, the zero time never expires.
+	Expires time.Time
+}
+
+// Queue is a notification routine that shows the notifications pushed to it in order of priority, one at a time. It
+// is safe to push to from any goroutine, such as the broker's http handlers.
+//
+// Waiting notifications are sent once the notification being shown should have finished, and are stacked behind it
+// on the device in case it has not. Urgent notifications are sent immediately without stacking, replacing the
+// notification being shown. The queue sets the Stack field of each notification it sends.
+type Queue struct {
+	Name         string
+	GlobalConfig awtrix.Config
+	PollRate     time.Duration
+	// RateLimit is the number of notifications each source can queue within RateLimitWindow, zero disables it.
+	RateLimit       int
+	RateLimitWindow time.Duration
+	// HoldDuration is how long a held notification is expected to be shown for, notifications that are not urgent
+	// are stacked behind it on the device once it passes.
+	HoldDuration time.Duration
+	mu           sync.Mutex
+	waiting      []*Notification
+	// sent records the times each source's recent notifications were queued, used to enforce the rate limit.
+	sent      map[string][]time.Time
+	showing   *Notification
+	busyUntil time.Time
+	next      *NotificationData
+	now       func() time.Time
+}
+
+// NewQueue instantiates a new notification queue routine.
+func NewQueue(name string) *Queue {
+	return &Queue{
+		Name:         name,
+		GlobalConfig: awtrix.Config{},
+		PollRate:     DefaultQueuePollRate,
+		HoldDuration: DefaultHoldDuration,
+		sent:         map[string][]time.Time{},
+		now:          time.Now,
+	}
+}
+
+// Push queues a notification, replacing a waiting notification with the same key. It returns ErrRateLimited when the
+// notification's source has exceeded the queue's rate limit. Replacing a waiting notification does not count towards
+// the rate limit, as it does not add to the notifications the source has waiting.
+func (q *Queue) Push(notification Notification) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if notification.Key != "" {
+		for i, waiting := range q.waiting {
+			if waiting.Key == notification.Key {
+				q.waiting[i] = &notification
+
+				return nil
+			}
+		}
+	}
+
+	now := q.now()
+
+	if q.RateLimit > 0 {
+		recent := slices.DeleteFunc(q.sent[notification.Source], func(sent time.Time) bool {
+			return now.Sub(sent) >= q.RateLimitWindow
+		})
+
+		if len(recent) >= q.RateLimit {
+			q.sent[notification.Source] = recent
+
+			return fmt.Errorf("%w: %v", ErrRateLimited, notification.Source)
+		}
+
+		q.sent[notification.Source] = append(recent, now)
+	}
+
+	q.waiting = append(q.waiting, &notification)
+
+	return nil
+}
+
+// Len returns the number of notifications waiting to be shown.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.waiting)
+}
+
+// Fetch takes the next notification to show from the queue, if the device is ready for it.
+func (q *Queue) Fetch(_ context.Context, _ *http.Client) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.next = nil
+
+	q.waiting = slices.DeleteFunc(q.waiting, func(waiting *Notification) bool {
+		expired := !waiting.Expires.IsZero() && !now.Before(waiting.Expires)
+		if expired {
+			slog.Debug("dropping expired notification", "queue", q.Name, "key", waiting.Key)
+		}
+
+		return expired
+	})
+
+	if len(q.waiting) == 0 {
+		return nil
+	}
+
+	// the notification that has waited longest among those with the highest priority
+	nextIndex := 0
+
+	for i, waiting := range q.waiting {
+		if waiting.Priority > q.waiting[nextIndex].Priority {
+			nextIndex = i
+		}
+	}
+
+	candidate := q.waiting[nextIndex]
+	busy := q.showing != nil && now.Before(q.busyUntil)
+	interrupt := busy && candidate.Priority >= PriorityUrgent && candidate.Priority > q.showing.Priority
+
+	if busy && !interrupt {
+		return nil
+	}
+
+	q.waiting = slices.Delete(q.waiting, nextIndex, nextIndex+1)
+
+	data := candidate.Data
+	stack := !interrupt
+	data.Stack = &stack
+
+	q.next = &data
+	q.showing = candidate
+	q.busyUntil = now.Add(q.displayDuration(data))
+
+	return nil
+}
+
+// displayDuration is how long the device is expected to show the notification for.
+func (q *Queue) displayDuration(data NotificationData) time.Duration {
+	if data.Hold != nil && *data.Hold {
+		return q.HoldDuration
+	}
+
+	if data.Duration != nil {
+		return time.Duration(*data.Duration) * time.Second
+	}
+
+	return DefaultNotificationDuration
+}
+
+// GetName returns the queue's name.
+func (q *Queue) GetName() string {
+	return q.Name
+}
+
+// GetPollRate returns the queue's poll rate.
+func (q *Queue) GetPollRate() time.Duration {
+	return q.PollRate
+}
+
+// GetData returns the notification taken from the queue by the last fetch.
+func (q *Queue) GetData() any {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.next
+}
+
+// Endpoint returns the Awtrix endpoint notifications are sent to.
+func (q *Queue) Endpoint() (awtrix.Endpoint, error) {
+	return awtrix.NotifyEndpoint(), nil
+}
+
+// GetGlobalConfig returns the global config this queue wishes to manipulate.
+func (q *Queue) GetGlobalConfig() awtrix.Config {
+	return q.GlobalConfig
+}
+
+// ShouldPushToAwtrix signals whether the last fetch took a notification from the queue.
+func (q *Queue) ShouldPushToAwtrix() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.next != nil
+}
@@ -0,0 +1,175 @@
+package notifier
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testQueue returns a queue whose clock is advanced by the returned function.
+func testQueue(t *testing.T) (*Queue, func(time.Duration)) {
+	t.Helper()
+
+	now := time.Date(2025, time.January, 1, 9, 0, 0, 0, time.UTC)
+	queue := NewQueue("alerts")
+	queue.now = func() time.Time { return now }
+
+	return queue, func(elapsed time.Duration) { now = now.Add(elapsed) }
+}
+
+// expectNext fetches from the queue, checking the notification it takes and whether it is stacked.
+func expectNext(t *testing.T, queue *Queue, text string, stacked bool) {
+	t.Helper()
+
+	err := queue.Fetch(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("should not throw error fetching\n\treceived error: %v", err)
+	}
+
+	if text == "" {
+		if queue.ShouldPushToAwtrix() {
+			t.Fatalf("queue should not have sent a notification\n\treceived: %v", queue.next.Text)
+		}
+
+		return
+	}
+
+	if !queue.ShouldPushToAwtrix() {
+		t.Fatalf("queue should have sent %q", text)
+	}
+
+	if queue.next.Text != text || *queue.next.Stack != stacked {
+		t.Fatalf("queue sent incorrect notification\n\texpected: %q stacked %v\n\treceived: %q stacked %v",
+			text, stacked, queue.next.Text, *queue.next.Stack)
+	}
+}
+
+func pushNotification(t *testing.T, queue *Queue, notification Notification) {
+	t.Helper()
+
+	err := queue.Push(notification)
+	if err != nil {
+		t.Fatalf("should not throw error queuing notification\n\treceived error: %v", err)
+	}
+}
+
+func Test_QueueOrdersByPriority(t *testing.T) {
+	t.Parallel()
+
+	queue, advance := testQueue(t)
+
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "low"}, Priority: PriorityLow})
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "first"}})
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "second"}})
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "high"}, Priority: PriorityHigh})
+
+	expectNext(t, queue, "high", true)
+
+	// waiting notifications wait for the notification being shown
+	expectNext(t, queue, "", false)
+
+	advance(DefaultNotificationDuration)
+	expectNext(t, queue, "first", true)
+
+	// urgent notifications interrupt the notification being shown
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "urgent"}, Priority: PriorityUrgent})
+	expectNext(t, queue, "urgent", false)
+
+	advance(DefaultNotificationDuration)
+	expectNext(t, queue, "second", true)
+
+	advance(DefaultNotificationDuration)
+	expectNext(t, queue, "low", true)
+}
+
+func Test_QueueDeduplicatesAndExpires(t *testing.T) {
+	t.Parallel()
+
+	queue, advance := testQueue(t)
+
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "build 1/3"}, Key: "build"})
+	pushNotification(t, queue, Notification{
+		Data:    NotificationData{Text: "standup"},
+		Expires: time.Date(2025, time.January, 1, 9, 0, 5, 0, time.UTC),
+	})
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "build 2/3"}, Key: "build"})
+
+	if waiting := queue.Len(); waiting != 2 {
+		t.Fatalf("queue should replace notifications with the same key\n\texpected: 2\n\treceived: %v", waiting)
+	}
+
+	expectNext(t, queue, "build 2/3", true)
+
+	advance(DefaultNotificationDuration)
+	expectNext(t, queue, "", false)
+
+	if waiting := queue.Len(); waiting != 0 {
+		t.Fatalf("queue should drop expired notifications\n\treceived: %v waiting", waiting)
+	}
+}
+
+func Test_QueueRateLimitsSources(t *testing.T) {
+	t.Parallel()
+
+	queue, advance := testQueue(t)
+	queue.RateLimit = 2
+	queue.RateLimitWindow = time.Minute
+
+	pushNotification(t, queue, Notification{Source: "ci"})
+	pushNotification(t, queue, Notification{Source: "ci"})
+	pushNotification(t, queue, Notification{Source: "calendar"})
+
+	err := queue.Push(Notification{Source: "ci"})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("did not throw expected error\n\texpected: %v\n\treceived: %v", ErrRateLimited, err)
+	}
+
+	advance(time.Minute)
+	pushNotification(t, queue, Notification{Source: "ci"})
+}
+
+func Test_QueueReplacementsAreNotRateLimited(t *testing.T) {
+	t.Parallel()
+
+	queue, _ := testQueue(t)
+	queue.RateLimit = 2
+	queue.RateLimitWindow = time.Minute
+
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "build 1/3"}, Key: "build", Source: "ci"})
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "build 2/3"}, Key: "build", Source: "ci"})
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "build 3/3"}, Key: "build", Source: "ci"})
+
+	// the source has only queued one notification, so it can still queue another
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "deploy"}, Source: "ci"})
+
+	err := queue.Push(Notification{Source: "ci"})
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("did not throw expected error\n\texpected: %v\n\treceived: %v", ErrRateLimited, err)
+	}
+
+	expectNext(t, queue, "build 3/3", true)
+}
+
+func Test_QueueWaitsForHeldNotifications(t *testing.T) {
+	t.Parallel()
+
+	queue, advance := testQueue(t)
+	hold := true
+	duration := 3
+
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "held", Hold: &hold}})
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "short", Duration: &duration}})
+
+	expectNext(t, queue, "held", true)
+
+	advance(DefaultNotificationDuration)
+	expectNext(t, queue, "", false)
+
+	advance(DefaultHoldDuration)
+	expectNext(t, queue, "short", true)
+
+	pushNotification(t, queue, Notification{Data: NotificationData{Text: "next"}})
+
+	advance(time.Duration(duration) * time.Second)
+	expectNext(t, queue, "next", true)
+}
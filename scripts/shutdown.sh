@@ -1 +1 @@
-curl 127.0.0.1:25827/admin/command -X POST -d "{\"command\":\"DOWN\"}"
+curl 127.0.0.1:25827/admin/command -X POST ${ALTAR_ADMIN_TOKEN:+-H "Authorization: Bearer $ALTAR_ADMIN_TOKEN"} -d "{\"command\":\"DOWN\"}"
@@ -0,0 +1,88 @@
+package awtrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Client talks to a single Awtrix device. HTTPClient implements it over the device's HTTP API, other implementations
+// may use another protocol such as MQTT, record requests for tests or fan requests out to several devices.
+type Client interface {
+	// Send delivers payload to the device's endpoint, a nil payload is sent without a body.
+	Send(ctx context.Context, endpoint Endpoint, payload []byte) error
+	// Stats returns the device's current stats.
+	Stats(ctx context.Context) (Stats, error)
+	// SetSettings changes the device's settings, settings left unset in cfg are not changed.
+	SetSettings(ctx context.Context, cfg Config) error
+	// PushApp creates or updates the custom app named name.
+	PushApp(ctx context.Context, name string, app any) error
+	// Notify shows a notification on the device.
+	Notify(ctx context.Context, notification any) error
+	// DismissNotification dismisses the notification the device is showing.
+	DismissNotification(ctx context.Context) error
+	// SetIndicator sets the state of one of the device's three indicators, numbered from 1 at the top.
+	SetIndicator(ctx context.Context, indicator int, state any) error
+	// Reboot restarts the device.
+	Reboot(ctx context.Context) error
+}
+
+// ErrClosingResponseBody describes the error encountered when a request fails whilst closing the response body.
+var ErrClosingResponseBody = errors.New("failed to close response body")
+
+// ErrMalformedStats occurs when the Awtrix device responds to a stats request with a body that is not valid stats.
+var ErrMalformedStats = errors.New("awtrix device responded with malformed stats")
+
+// SettingsEndpoint returns the endpoint that changes the device's settings.
+func SettingsEndpoint() Endpoint {
+	return Endpoint{Method: http.MethodPost, Path: "/api/settings"}
+}
+
+// CustomAppEndpoint returns the endpoint of the custom app named name.
+func CustomAppEndpoint(name string) Endpoint {
+	return Endpoint{Method: http.MethodPost, Path: "/api/custom", Query: url.Values{"name": {name}}, Retained: true}
+}
+
+// NotifyEndpoint returns the endpoint that shows notifications.
+func NotifyEndpoint() Endpoint {
+	return Endpoint{Method: http.MethodPost, Path: "/api/notify"}
+}
+
+// DismissNotificationEndpoint returns the endpoint that dismisses the notification being shown.
+func DismissNotificationEndpoint() Endpoint {
+	return Endpoint{Method: http.MethodPost, Path: "/api/notify/dismiss"}
+}
+
+// IndicatorEndpoint returns the endpoint of the indicator numbered indicator, from 1 at the top.
+func IndicatorEndpoint(indicator int) Endpoint {
+	return Endpoint{Method: http.MethodPost, Path: fmt.Sprintf("/api/indicator%d", indicator), Retained: true}
+}
+
+// RebootEndpoint returns the endpoint that restarts the device.
+func RebootEndpoint() Endpoint {
+	return Endpoint{Method: http.MethodPost, Path: "/api/reboot"}
+}
+
+// StatsEndpoint returns the endpoint that reports the device's stats.
+func StatsEndpoint() Endpoint {
+	return Endpoint{Method: http.MethodGet, Path: "/api/stats"}
+}
+
+// SendJSON marshals value into json and sends it to the client's endpoint, it lets implementations of Client build
+// their typed methods on Send.
+func SendJSON(ctx context.Context, client Client, endpoint Endpoint, value any) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %v payload into json: %w", endpoint.Path, err)
+	}
+
+	err = client.Send(ctx, endpoint, payload)
+	if err != nil {
+		return fmt.Errorf("failed to send %v: %w", endpoint.Path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package awtrix
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Endpoint describes the request a routine's data is sent to the Awtrix device with.
+type Endpoint struct {
+	// Method is the http method of the request, defaulting to POST.
+	Method string
+	// Path is the path of the Awtrix API endpoint, e.g. "/api/custom".
+	Path string
+	// Query holds the query parameters of the request, e.g. the name of a custom app.
+	Query url.Values
+	// Retained marks endpoints whose state the Awtrix device keeps until it is changed, but loses when it reboots.
+	// Retained state is re-sent after the device reboots, whereas transient requests such as notifications are not.
+	Retained bool
+}
+
+// RequestMethod returns the endpoint's http method, defaulting to POST.
+func (e Endpoint) RequestMethod() string {
+	if e.Method == "" {
+		return http.MethodPost
+	}
+
+	return e.Method
+}
+
+// RequestURI returns the endpoint's path and encoded query.
+func (e Endpoint) RequestURI() string {
+	if len(e.Query) == 0 {
+		return e.Path
+	}
+
+	return e.Path + "?" + e.Query.Encode()
+}
+
+// Idempotent reports whether the device acting on the request twice has the same effect as acting on it once, making
+// the request safe to retry after a transport error that may have occurred once the device had already acted on it.
+// Reads, settings and requests replacing retained state are idempotent, while notifications, dismissals and reboots
+// are not.
+func (e Endpoint) Idempotent() bool {
+	return e.Retained || e.RequestMethod() == http.MethodGet || e.Path == SettingsEndpoint().Path
+}
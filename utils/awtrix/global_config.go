@@ -1,17 +1,49 @@
 // Package awtrix provides utilities for awtrix related logic.
 package awtrix
 
-// Config defines the configuration options for an Awtrix device.
+// Config defines the configuration options for an Awtrix device, colours are RGB color values [R,G,B].
+//
+//nolint:tagliatelle // the native app colour keys are snake case, such as TIME_COL, while the rest are upper case
 type Config struct {
 	// https://blueforcer.github.io/awtrix3/#/api?id=json-properties-1
-	TimeAppEnabled     *bool   `json:"TIM,omitempty"`
-	WeekdayAppEnabled  *bool   `json:"WD,omitempty"`
-	DateAppEnabled     *bool   `json:"DAT,omitempty"`
-	HumidityAppEnabled *bool   `json:"HUM,omitempty"`
-	TempAppEnabled     *bool   `json:"TEMP,omitempty"`
-	BatteryAppEnabled  *bool   `json:"BAT,omitempty"`
-	Overlay            Overlay `json:"OVERLAY,omitempty"`
-	TransitionEffect   *int    `json:"TEFF,omitempty"`
+	TimeAppEnabled        *bool             `json:"TIM,omitempty"`
+	WeekdayAppEnabled     *bool             `json:"WD,omitempty"`
+	DateAppEnabled        *bool             `json:"DAT,omitempty"`
+	HumidityAppEnabled    *bool             `json:"HUM,omitempty"`
+	TempAppEnabled        *bool             `json:"TEMP,omitempty"`
+	BatteryAppEnabled     *bool             `json:"BAT,omitempty"`
+	Overlay               Overlay           `json:"OVERLAY,omitempty"`
+	TransitionEffect      *TransitionEffect `json:"TEFF,omitempty"`
+	TransitionSpeed       *int              `json:"TSPEED,omitempty"` // milliseconds taken to transition between apps
+	AppDuration           *int              `json:"ATIME,omitempty"`  // seconds each app is displayed for
+	AutoTransition        *bool             `json:"ATRANS,omitempty"`
+	Brightness            *int              `json:"BRI,omitempty"` // 0-255
+	AutoBrightness        *bool             `json:"ABRI,omitempty"`
+	TextColour            []int             `json:"TCOL,omitempty"`
+	ColourCorrection      []int             `json:"CCORRECTION,omitempty"`
+	ColourTemperature     []int             `json:"CTEMP,omitempty"`
+	Gamma                 *float64          `json:"GAMMA,omitempty"`
+	Uppercase             *bool             `json:"UPPERCASE,omitempty"`
+	ScrollSpeed           *int              `json:"SSPEED,omitempty"` // percentage of the default scroll speed
+	TimeMode              *TimeMode         `json:"TMODE,omitempty"`
+	TimeFormat            string            `json:"TFORMAT,omitempty"`
+	DateFormat            string            `json:"DFORMAT,omitempty"`
+	StartOnMonday         *bool             `json:"SOM,omitempty"`
+	Celsius               *bool             `json:"CEL,omitempty"`
+	CalendarHeaderColour  []int             `json:"CHCOL,omitempty"`
+	CalendarBodyColour    []int             `json:"CBCOL,omitempty"`
+	CalendarTextColour    []int             `json:"CTCOL,omitempty"`
+	ActiveWeekdayColour   []int             `json:"WDCA,omitempty"`
+	InactiveWeekdayColour []int             `json:"WDCI,omitempty"`
+	TimeAppColour         []int             `json:"TIME_COL,omitempty"`
+	DateAppColour         []int             `json:"DATE_COL,omitempty"`
+	TempAppColour         []int             `json:"TEMP_COL,omitempty"`
+	HumidityAppColour     []int             `json:"HUM_COL,omitempty"`
+	BatteryAppColour      []int             `json:"BAT_COL,omitempty"`
+	MatrixLayout          *MatrixLayout     `json:"MAT,omitempty"`
+	BlockNavigationKeys   *bool             `json:"BLOCKN,omitempty"`
+	SoundEnabled          *bool             `json:"SOUND,omitempty"`
+	Volume                *int              `json:"VOL,omitempty"` // 0-30
 }
 
 // Overlay represents the set of available overlays for Awtrix devices.
@@ -22,4 +54,84 @@ const (
 	Rain Overlay = "rain"
 	// Clear will remove any previously set overlays.
 	Clear Overlay = "clear"
+	// Snow will present falling snow over the display.
+	Snow Overlay = "snow"
+	// Drizzle will present light rain over the display.
+	Drizzle Overlay = "drizzle"
+	// Storm will present heavy rain over the display.
+	Storm Overlay = "storm"
+	// Thunder will present lightning over the display.
+	Thunder Overlay = "thunder"
+	// Frost will present frost over the display.
+	Frost Overlay = "frost"
 )
+
+// TransitionEffect represents the effects Awtrix uses to transition between apps.
+type TransitionEffect int
+
+const (
+	// RandomTransition picks a different effect for each transition.
+	RandomTransition TransitionEffect = iota
+	// SlideTransition slides the next app onto the display.
+	SlideTransition
+	// DimTransition dims the display between apps.
+	DimTransition
+	// ZoomTransition zooms into the next app.
+	ZoomTransition
+	// RotateTransition rotates the next app onto the display.
+	RotateTransition
+	// PixelateTransition pixelates the display between apps.
+	PixelateTransition
+	// CurtainTransition draws the next app across the display like a curtain.
+	CurtainTransition
+	// RippleTransition ripples the next app onto the display.
+	RippleTransition
+	// BlinkTransition blinks between apps.
+	BlinkTransition
+	// ReloadTransition reloads the display between apps.
+	ReloadTransition
+	// FadeTransition fades between apps.
+	FadeTransition
+)
+
+// TimeMode represents the styles available to the native time app, ranging from 0 to 6.
+//
+// https://blueforcer.github.io/awtrix3/#/api?id=time-modes
+type TimeMode int
+
+// MatrixLayout represents the wiring layouts of the LED matrix, ranging from 0 to 2.
+type MatrixLayout int
+
+const (
+	defaultAppDurationSeconds = 7
+	defaultScrollSpeed        = 100
+)
+
+// DefaultConfig returns the documented firmware defaults for the settings that only affect what the Awtrix device
+// displays. Settings that depend on the device's hardware, such as the matrix layout, are left unset.
+func DefaultConfig() Config {
+	enabled := true
+	disabled := false
+	slide := SlideTransition
+	appDuration := defaultAppDurationSeconds
+	scrollSpeed := defaultScrollSpeed
+
+	return Config{
+		TimeAppEnabled:      &enabled,
+		WeekdayAppEnabled:   &enabled,
+		DateAppEnabled:      &enabled,
+		HumidityAppEnabled:  &enabled,
+		TempAppEnabled:      &enabled,
+		BatteryAppEnabled:   &enabled,
+		Overlay:             Clear,
+		TransitionEffect:    &slide,
+		AppDuration:         &appDuration,
+		AutoTransition:      &enabled,
+		Uppercase:           &enabled,
+		ScrollSpeed:         &scrollSpeed,
+		StartOnMonday:       &enabled,
+		Celsius:             &enabled,
+		BlockNavigationKeys: &disabled,
+		SoundEnabled:        &enabled,
+	}
+}
@@ -0,0 +1,221 @@
+package awtrix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// https://blueforcer.github.io/awtrix3/#/api
+
+//nolint:tagliatelle // awtrix's command bodies use lower case keys, unlike its upper case settings
+type (
+	// PowerRequest turns the device's matrix on or off.
+	PowerRequest struct {
+		Power bool `json:"power"`
+	}
+
+	// SleepRequest puts the device into deep sleep, waking after Sleep seconds or when the middle button is pressed.
+	SleepRequest struct {
+		Sleep int `json:"sleep"`
+	}
+
+	// SoundRequest plays a melody stored on the device.
+	SoundRequest struct {
+		Sound string `json:"sound"`
+	}
+
+	// SwitchAppRequest switches the display to an app in the loop.
+	SwitchAppRequest struct {
+		Name string `json:"name"`
+	}
+
+	// Moodlight fills the matrix with a single colour, either Color or a colour temperature in Kelvin.
+	Moodlight struct {
+		Brightness int   `json:"brightness,omitempty"`
+		Kelvin     int   `json:"kelvin,omitempty"`
+		Color      []int `json:"color,omitempty"`
+	}
+)
+
+// Settings is the device's current settings as reported by its settings endpoint. Unlike Config, which is sent to the
+// device, colours are reported as 24 bit RGB values such as 0xFF0000 for red.
+//
+//nolint:tagliatelle // the firmware reports the native app colours with snake case keys such as TIME_COL
+type Settings struct {
+	MatrixOn              bool             `json:"MATP"`
+	AutoBrightness        bool             `json:"ABRI"`
+	Brightness            int              `json:"BRI"`
+	AutoTransition        bool             `json:"ATRANS"`
+	TextColour            int              `json:"TCOL"`
+	TransitionEffect      TransitionEffect `json:"TEFF"`
+	TransitionSpeed       int              `json:"TSPEED"`
+	AppDuration           int              `json:"ATIME"`
+	TimeMode              TimeMode         `json:"TMODE"`
+	CalendarHeaderColour  int              `json:"CHCOL"`
+	CalendarTextColour    int              `json:"CTCOL"`
+	CalendarBodyColour    int              `json:"CBCOL"`
+	TimeFormat            string           `json:"TFORMAT"`
+	DateFormat            string           `json:"DFORMAT"`
+	StartOnMonday         bool             `json:"SOM"`
+	Celsius               bool             `json:"CEL"`
+	BlockNavigationKeys   bool             `json:"BLOCKN"`
+	MatrixLayout          MatrixLayout     `json:"MAT"`
+	SoundEnabled          bool             `json:"SOUND"`
+	Gamma                 float64          `json:"GAMMA"`
+	Uppercase             bool             `json:"UPPERCASE"`
+	ColourCorrection      []int            `json:"CCORRECTION"`
+	ColourTemperature     []int            `json:"CTEMP"`
+	WeekdayAppEnabled     bool             `json:"WD"`
+	ActiveWeekdayColour   int              `json:"WDCA"`
+	InactiveWeekdayColour int              `json:"WDCI"`
+	TimeAppColour         int              `json:"TIME_COL"`
+	DateAppColour         int              `json:"DATE_COL"`
+	HumidityAppColour     int              `json:"HUM_COL"`
+	TempAppColour         int              `json:"TEMP_COL"`
+	BatteryAppColour      int              `json:"BAT_COL"`
+	ScrollSpeed           int              `json:"SSPEED"`
+	TimeAppEnabled        bool             `json:"TIM"`
+	DateAppEnabled        bool             `json:"DAT"`
+	HumidityAppEnabled    bool             `json:"HUM"`
+	TempAppEnabled        bool             `json:"TEMP"`
+	BatteryAppEnabled     bool             `json:"BAT"`
+	Volume                int              `json:"VOL"`
+}
+
+// Loop maps the name of each app in the device's loop to its position.
+type Loop map[string]int
+
+// Effects returns the names of the effects available as app backgrounds.
+func (c *HTTPClient) Effects(ctx context.Context) ([]string, error) {
+	effects := []string{}
+
+	err := c.get(ctx, "/api/effects", &effects)
+
+	return effects, err
+}
+
+// Transitions returns the names of the transition effects available between apps.
+func (c *HTTPClient) Transitions(ctx context.Context) ([]string, error) {
+	transitions := []string{}
+
+	err := c.get(ctx, "/api/transitions", &transitions)
+
+	return transitions, err
+}
+
+// Loop returns the apps in the device's loop.
+func (c *HTTPClient) Loop(ctx context.Context) (Loop, error) {
+	loop := Loop{}
+
+	err := c.get(ctx, "/api/loop", &loop)
+
+	return loop, err
+}
+
+// Screen returns the colour of each pixel on the matrix as a 24 bit RGB value, row by row from the top left.
+func (c *HTTPClient) Screen(ctx context.Context) ([]int, error) {
+	screen := []int{}
+
+	err := c.get(ctx, "/api/screen", &screen)
+
+	return screen, err
+}
+
+// Settings returns the device's current settings.
+func (c *HTTPClient) Settings(ctx context.Context) (Settings, error) {
+	settings := Settings{}
+
+	err := c.get(ctx, "/api/settings", &settings)
+
+	return settings, err
+}
+
+// RemoveApp removes the custom app named name from the device's loop.
+func (c *HTTPClient) RemoveApp(ctx context.Context, name string) error {
+	return c.Send(ctx, CustomAppEndpoint(name), []byte{})
+}
+
+// SwitchApp switches the display to the app named name.
+func (c *HTTPClient) SwitchApp(ctx context.Context, name string) error {
+	return SendJSON(ctx, c, Endpoint{Path: "/api/switch"}, SwitchAppRequest{Name: name})
+}
+
+// NextApp switches the display to the next app in the loop.
+func (c *HTTPClient) NextApp(ctx context.Context) error {
+	return c.Send(ctx, Endpoint{Path: "/api/nextapp"}, nil)
+}
+
+// PreviousApp switches the display to the previous app in the loop.
+func (c *HTTPClient) PreviousApp(ctx context.Context) error {
+	return c.Send(ctx, Endpoint{Path: "/api/previousapp"}, nil)
+}
+
+// ReorderApps reorders the apps in the loop, apps left out keep their positions after the named apps.
+func (c *HTTPClient) ReorderApps(ctx context.Context, names []string) error {
+	return SendJSON(ctx, c, Endpoint{Path: "/api/reorder"}, names)
+}
+
+// SetPower turns the matrix on or off.
+func (c *HTTPClient) SetPower(ctx context.Context, on bool) error {
+	return SendJSON(ctx, c, Endpoint{Path: "/api/power"}, PowerRequest{Power: on})
+}
+
+// Sleep puts the device into deep sleep for duration, rounded down to the second.
+func (c *HTTPClient) Sleep(ctx context.Context, duration time.Duration) error {
+	return SendJSON(ctx, c, Endpoint{Path: "/api/sleep"}, SleepRequest{Sleep: int(duration / time.Second)})
+}
+
+// PlaySound plays the melody stored on the device under name.
+func (c *HTTPClient) PlaySound(ctx context.Context, name string) error {
+	return SendJSON(ctx, c, Endpoint{Path: "/api/sound"}, SoundRequest{Sound: name})
+}
+
+// PlayRTTTL plays a melody in the RTTTL format.
+func (c *HTTPClient) PlayRTTTL(ctx context.Context, melody string) error {
+	return c.do(ctx, Endpoint{Path: "/api/rtttl"}, []byte(melody), "text/plain", nil)
+}
+
+// PlayR2D2 plays a random R2D2 sound.
+func (c *HTTPClient) PlayR2D2(ctx context.Context) error {
+	return c.Send(ctx, Endpoint{Path: "/api/r2d2"}, nil)
+}
+
+// SetMoodlight fills the matrix with the moodlight, hiding the loop until it is cleared.
+func (c *HTTPClient) SetMoodlight(ctx context.Context, moodlight Moodlight) error {
+	return SendJSON(ctx, c, Endpoint{Path: "/api/moodlight"}, moodlight)
+}
+
+// ClearMoodlight turns the moodlight off, returning the display to the loop.
+func (c *HTTPClient) ClearMoodlight(ctx context.Context) error {
+	return c.Send(ctx, Endpoint{Path: "/api/moodlight"}, []byte{})
+}
+
+// ResetSettings restores the device's settings to their defaults, keeping its WiFi settings.
+func (c *HTTPClient) ResetSettings(ctx context.Context) error {
+	return c.Send(ctx, Endpoint{Path: "/api/resetSettings"}, nil)
+}
+
+// Erase factory resets the device, erasing its flash memory and WiFi settings.
+func (c *HTTPClient) Erase(ctx context.Context) error {
+	return c.Send(ctx, Endpoint{Path: "/api/erase"}, nil)
+}
+
+// UpdateFirmware has the device download and install its latest firmware.
+func (c *HTTPClient) UpdateFirmware(ctx context.Context) error {
+	return c.Send(ctx, Endpoint{Path: "/api/doupdate"}, nil)
+}
+
+// get requests the device's endpoint at path, decoding its json response into value.
+func (c *HTTPClient) get(ctx context.Context, path string, value any) error {
+	return c.do(ctx, Endpoint{Method: http.MethodGet, Path: path}, nil, "", func(body []byte) error {
+		err := json.Unmarshal(body, value)
+		if err != nil {
+			return fmt.Errorf("failed to decode %v response: %w", path, err)
+		}
+
+		return nil
+	})
+}
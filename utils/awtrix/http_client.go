@@ -0,0 +1,165 @@
+package awtrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const jsonContent = "application/json"
+
+// ErrInvalidBaseURL occurs when an HTTPClient is created with a base URL that is not an http or https URL.
+var ErrInvalidBaseURL = errors.New("awtrix base URL must be an http or https URL with a host")
+
+// HTTPClient talks to an Awtrix device over its HTTP API.
+type HTTPClient struct {
+	// BaseURL is the base URL of the device's API, such as "http://192.168.0.10". Its path is used as a prefix for
+	// every endpoint.
+	BaseURL string
+	// HTTP performs the client's requests, http.DefaultClient is used when it is nil.
+	HTTP *http.Client
+	// Username and Password authenticate each request with HTTP basic auth when Username is set.
+	Username string
+	Password string
+}
+
+// NewHTTPClient creates a client for the device at baseURL, credentials in baseURL are used for HTTP basic auth.
+func NewHTTPClient(baseURL string, client *http.Client) (*HTTPClient, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBaseURL, baseURL)
+	}
+
+	httpClient := &HTTPClient{HTTP: client}
+
+	if parsed.User != nil {
+		httpClient.Username = parsed.User.Username()
+		httpClient.Password, _ = parsed.User.Password()
+		parsed.User = nil
+	}
+
+	httpClient.BaseURL = parsed.String()
+
+	return httpClient, nil
+}
+
+// Send makes a request to the device's endpoint. A non-2xx response is returned as a *StatusError.
+func (c *HTTPClient) Send(ctx context.Context, endpoint Endpoint, payload []byte) error {
+	return c.do(ctx, endpoint, payload, jsonContent, nil)
+}
+
+// Stats requests the device's stats.
+func (c *HTTPClient) Stats(ctx context.Context) (Stats, error) {
+	stats := Stats{}
+
+	err := c.do(ctx, StatsEndpoint(), nil, "", func(body []byte) error {
+		err := json.Unmarshal(body, &stats)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrMalformedStats, err)
+		}
+
+		return nil
+	})
+
+	return stats, err
+}
+
+// SetSettings changes the device's settings, settings left unset in cfg are not changed.
+func (c *HTTPClient) SetSettings(ctx context.Context, cfg Config) error {
+	return SendJSON(ctx, c, SettingsEndpoint(), cfg)
+}
+
+// PushApp creates or updates the custom app named name.
+func (c *HTTPClient) PushApp(ctx context.Context, name string, app any) error {
+	return SendJSON(ctx, c, CustomAppEndpoint(name), app)
+}
+
+// Notify shows a notification on the device.
+func (c *HTTPClient) Notify(ctx context.Context, notification any) error {
+	return SendJSON(ctx, c, NotifyEndpoint(), notification)
+}
+
+// DismissNotification dismisses the notification the device is showing.
+func (c *HTTPClient) DismissNotification(ctx context.Context) error {
+	return c.Send(ctx, DismissNotificationEndpoint(), nil)
+}
+
+// SetIndicator sets the state of one of the device's three indicators, numbered from 1 at the top.
+func (c *HTTPClient) SetIndicator(ctx context.Context, indicator int, state any) error {
+	return SendJSON(ctx, c, IndicatorEndpoint(indicator), state)
+}
+
+// Reboot restarts the device.
+func (c *HTTPClient) Reboot(ctx context.Context) error {
+	return c.Send(ctx, RebootEndpoint(), nil)
+}
+
+// do makes a request to the device's endpoint, passing the body of a 2xx response to read when it is not nil.
+func (c *HTTPClient) do(
+	ctx context.Context,
+	endpoint Endpoint,
+	payload []byte,
+	contentType string,
+	read func([]byte) error,
+) (err error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	address := strings.TrimSuffix(c.BaseURL, "/") + endpoint.RequestURI()
+
+	req, err := http.NewRequestWithContext(ctx, endpoint.RequestMethod(), address, body)
+	if err != nil {
+		return fmt.Errorf("failed to create %v request: %w", endpoint.RequestMethod(), err)
+	}
+
+	if payload != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to perform %v request: %w", req.Method, err)
+	}
+
+	defer func() {
+		closeErr := resp.Body.Close()
+		if err == nil && closeErr != nil {
+			err = fmt.Errorf("%w: %w", ErrClosingResponseBody, closeErr)
+		}
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		// drains the body so the connection can be reused
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		return &StatusError{Endpoint: req.URL.Path, StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %v response: %w", endpoint.Path, err)
+	}
+
+	if read == nil {
+		return nil
+	}
+
+	return read(respBody)
+}
@@ -0,0 +1,292 @@
+package awtrix_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/t-monaghan/altar/utils/awtrix"
+)
+
+type recordedRequest struct {
+	method      string
+	uri         string
+	contentType string
+	body        string
+}
+
+// startDevice starts a fake device that records each request and responds with response.
+func startDevice(t *testing.T, status int, response string) (*awtrix.HTTPClient, <-chan recordedRequest) {
+	t.Helper()
+
+	requests := make(chan recordedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- recordedRequest{r.Method, r.URL.RequestURI(), r.Header.Get("Content-Type"), string(body)}
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+
+	t.Cleanup(server.Close)
+
+	client, err := awtrix.NewHTTPClient(server.URL, server.Client())
+	if err != nil {
+		t.Fatalf("should not throw error creating client\n\treceived error: %v", err)
+	}
+
+	return client, requests
+}
+
+func Test_HTTPClientSendsCommands(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		call     func(context.Context, *awtrix.HTTPClient) error
+		expected recordedRequest
+	}{
+		{
+			"switch app",
+			func(ctx context.Context, c *awtrix.HTTPClient) error { return c.SwitchApp(ctx, "weather") },
+			recordedRequest{http.MethodPost, "/api/switch", "application/json", `{"name":"weather"}`},
+		},
+		{
+			"next app",
+			func(ctx context.Context, c *awtrix.HTTPClient) error { return c.NextApp(ctx) },
+			recordedRequest{http.MethodPost, "/api/nextapp", "", ""},
+		},
+		{
+			"previous app",
+			func(ctx context.Context, c *awtrix.HTTPClient) error { return c.PreviousApp(ctx) },
+			recordedRequest{http.MethodPost, "/api/previousapp", "", ""},
+		},
+		{
+			"reorder apps",
+			func(ctx context.Context, c *awtrix.HTTPClient) error {
+				return c.ReorderApps(ctx, []string{"Time", "weather"})
+			},
+			recordedRequest{http.MethodPost, "/api/reorder", "application/json", `["Time","weather"]`},
+		},
+		{
+			"power",
+			func(ctx context.Context, c *awtrix.HTTPClient) error { return c.SetPower(ctx, false) },
+			recordedRequest{http.MethodPost, "/api/power", "application/json", `{"power":false}`},
+		},
+		{
+			"sleep",
+			func(ctx context.Context, c *awtrix.HTTPClient) error { return c.Sleep(ctx, 90*time.Second) },
+			recordedRequest{http.MethodPost, "/api/sleep", "application/json", `{"sleep":90}`},
+		},
+		{
+			"sound",
+			func(ctx context.Context, c *awtrix.HTTPClient) error { return c.PlaySound(ctx, "alarm") },
+			recordedRequest{http.MethodPost, "/api/sound", "application/json", `{"sound":"alarm"}`},
+		},
+		{
+			"rtttl",
+			func(ctx context.Context, c *awtrix.HTTPClient) error { return c.PlayRTTTL(ctx, "beep:d=4,o=5,b=100:c") },
+			recordedRequest{http.MethodPost, "/api/rtttl", "text/plain", "beep:d=4,o=5,b=100:c"},
+		},
+		{
+			"moodlight",
+			func(ctx context.Context, c *awtrix.HTTPClient) error {
+				return c.SetMoodlight(ctx, awtrix.Moodlight{Brightness: 100, Kelvin: 2300})
+			},
+			recordedRequest{http.MethodPost, "/api/moodlight", "application/json", `{"brightness":100,"kelvin":2300}`},
+		},
+		{
+			"clear moodlight",
+			func(ctx context.Context, c *awtrix.HTTPClient) error { return c.ClearMoodlight(ctx) },
+			recordedRequest{http.MethodPost, "/api/moodlight", "application/json", ""},
+		},
+		{
+			"dismiss notification",
+			func(ctx context.Context, c *awtrix.HTTPClient) error { return c.DismissNotification(ctx) },
+			recordedRequest{http.MethodPost, "/api/notify/dismiss", "", ""},
+		},
+		{
+			"indicator",
+			func(ctx context.Context, c *awtrix.HTTPClient) error {
+				return c.SetIndicator(ctx, 2, map[string]any{"color": []int{255, 0, 0}})
+			},
+			recordedRequest{http.MethodPost, "/api/indicator2", "application/json", `{"color":[255,0,0]}`},
+		},
+		{
+			"custom app",
+			func(ctx context.Context, c *awtrix.HTTPClient) error {
+				return c.PushApp(ctx, "weather", map[string]any{"text": "sunny"})
+			},
+			recordedRequest{http.MethodPost, "/api/custom?name=weather", "application/json", `{"text":"sunny"}`},
+		},
+		{
+			"remove app",
+			func(ctx context.Context, c *awtrix.HTTPClient) error { return c.RemoveApp(ctx, "weather") },
+			recordedRequest{http.MethodPost, "/api/custom?name=weather", "application/json", ""},
+		},
+		{
+			"update firmware",
+			func(ctx context.Context, c *awtrix.HTTPClient) error { return c.UpdateFirmware(ctx) },
+			recordedRequest{http.MethodPost, "/api/doupdate", "", ""},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, requests := startDevice(t, http.StatusOK, "OK")
+
+			err := testCase.call(t.Context(), client)
+			if err != nil {
+				t.Fatalf("should not throw error\n\treceived error: %v", err)
+			}
+
+			if received := <-requests; received != testCase.expected {
+				t.Fatalf("client sent incorrect request\n\texpected: %+v\n\treceived: %+v", testCase.expected, received)
+			}
+		})
+	}
+}
+
+// recordedSettings is a response from an Awtrix 3 device's settings endpoint, which reports colours as 24 bit values.
+const recordedSettings = `{"MATP":true,"ABRI":false,"BRI":120,"ATRANS":true,"TCOL":16777215,"TEFF":1,` +
+	`"TSPEED":400,"ATIME":7,"TMODE":1,"CHCOL":16711680,"CTCOL":0,"CBCOL":16777215,"TFORMAT":"%H:%M:%S",` +
+	`"DFORMAT":"%d.%m.%y","SOM":true,"CEL":true,"BLOCKN":false,"MAT":0,"SOUND":true,"GAMMA":0,` +
+	`"UPPERCASE":true,"CCORRECTION":[255,255,255],"CTEMP":[255,255,255],"WD":true,"WDCA":16777215,` +
+	`"WDCI":6710886,"TIME_COL":65280,"DATE_COL":0,"HUM_COL":0,"TEMP_COL":0,"BAT_COL":0,"SSPEED":100,` +
+	`"TIM":true,"DAT":true,"HUM":true,"TEMP":true,"BAT":true,"VOL":15}`
+
+func Test_HTTPClientReadsResponses(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		response string
+		uri      string
+		call     func(context.Context, *awtrix.HTTPClient) (any, error)
+		expected any
+	}{
+		{
+			"effects", `["Fade","Matrix"]`, "/api/effects",
+			func(ctx context.Context, c *awtrix.HTTPClient) (any, error) { return c.Effects(ctx) },
+			[]string{"Fade", "Matrix"},
+		},
+		{
+			"transitions", `["Random","Slide"]`, "/api/transitions",
+			func(ctx context.Context, c *awtrix.HTTPClient) (any, error) { return c.Transitions(ctx) },
+			[]string{"Random", "Slide"},
+		},
+		{
+			"loop", `{"Time":0,"weather":1}`, "/api/loop",
+			func(ctx context.Context, c *awtrix.HTTPClient) (any, error) { return c.Loop(ctx) },
+			awtrix.Loop{"Time": 0, "weather": 1},
+		},
+		{
+			"screen", `[0,16777215]`, "/api/screen",
+			func(ctx context.Context, c *awtrix.HTTPClient) (any, error) { return c.Screen(ctx) },
+			[]int{0, 16777215},
+		},
+		{
+			"settings", recordedSettings, "/api/settings",
+			func(ctx context.Context, c *awtrix.HTTPClient) (any, error) { return c.Settings(ctx) },
+			awtrix.Settings{
+				MatrixOn: true, Brightness: 120, AutoTransition: true, TextColour: 0xFFFFFF,
+				TransitionEffect: awtrix.SlideTransition, TransitionSpeed: 400, AppDuration: 7, TimeMode: 1,
+				CalendarHeaderColour: 0xFF0000, CalendarBodyColour: 0xFFFFFF, TimeFormat: "%H:%M:%S",
+				DateFormat: "%d.%m.%y", StartOnMonday: true, Celsius: true, SoundEnabled: true, Uppercase: true,
+				ColourCorrection: []int{255, 255, 255}, ColourTemperature: []int{255, 255, 255},
+				WeekdayAppEnabled: true, ActiveWeekdayColour: 0xFFFFFF, InactiveWeekdayColour: 0x666666,
+				TimeAppColour: 0x00FF00, ScrollSpeed: 100, TimeAppEnabled: true, DateAppEnabled: true,
+				HumidityAppEnabled: true, TempAppEnabled: true, BatteryAppEnabled: true, Volume: 15,
+			},
+		},
+		{
+			"stats", `{"bat":87,"uptime":321,"version":"0.98"}`, "/api/stats",
+			func(ctx context.Context, c *awtrix.HTTPClient) (any, error) { return c.Stats(ctx) },
+			awtrix.Stats{Battery: 87, Uptime: 321, Version: "0.98"},
+		},
+	}
+
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			client, requests := startDevice(t, http.StatusOK, testCase.response)
+
+			received, err := testCase.call(t.Context(), client)
+			if err != nil {
+				t.Fatalf("should not throw error\n\treceived error: %v", err)
+			}
+
+			if !reflect.DeepEqual(received, testCase.expected) {
+				t.Fatalf("client decoded incorrect response\n\texpected: %+v\n\treceived: %+v",
+					testCase.expected, received)
+			}
+
+			if request := <-requests; request.method != http.MethodGet || request.uri != testCase.uri {
+				t.Fatalf("client sent incorrect request: %v %v", request.method, request.uri)
+			}
+		})
+	}
+}
+
+func Test_HTTPClientReportsErrors(t *testing.T) {
+	t.Parallel()
+
+	client, _ := startDevice(t, http.StatusNotFound, "Not found")
+
+	err := client.NextApp(t.Context())
+
+	statusErr := &awtrix.StatusError{}
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("did not throw expected status error\n\treceived: %v", err)
+	}
+
+	client, _ = startDevice(t, http.StatusOK, "not json")
+
+	_, err = client.Stats(t.Context())
+	if !errors.Is(err, awtrix.ErrMalformedStats) {
+		t.Fatalf("did not throw expected error\n\texpected: %v\n\treceived: %v", awtrix.ErrMalformedStats, err)
+	}
+
+	_, err = awtrix.NewHTTPClient("ftp://192.168.0.10", nil)
+	if !errors.Is(err, awtrix.ErrInvalidBaseURL) {
+		t.Fatalf("did not throw expected error\n\texpected: %v\n\treceived: %v", awtrix.ErrInvalidBaseURL, err)
+	}
+}
+
+func Test_HTTPClientAuthenticates(t *testing.T) {
+	t.Parallel()
+
+	credentials := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		credentials <- username + ":" + password
+	}))
+
+	t.Cleanup(server.Close)
+
+	client, err := awtrix.NewHTTPClient("http://admin:secret@"+server.Listener.Addr().String()+"/", nil)
+	if err != nil {
+		t.Fatalf("should not throw error creating client\n\treceived error: %v", err)
+	}
+
+	if client.BaseURL != server.URL+"/" {
+		t.Fatalf("client should strip credentials from its base URL\n\treceived: %v", client.BaseURL)
+	}
+
+	err = client.Reboot(t.Context())
+	if err != nil {
+		t.Fatalf("should not throw error\n\treceived error: %v", err)
+	}
+
+	if received := <-credentials; received != "admin:secret" {
+		t.Fatalf("client sent incorrect credentials: %v", received)
+	}
+}
@@ -0,0 +1,142 @@
+package awtrix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ErrNon2xxResponse is matched by every StatusError, allowing callers to check for any non-2xx response with errors.Is.
+var ErrNon2xxResponse = errors.New("awtrix responded with non-2xx http status")
+
+// StatusError describes a non-2xx response from an Awtrix device.
+type StatusError struct {
+	// Endpoint is the path of the request that failed, e.g. "/api/custom".
+	Endpoint   string
+	StatusCode int
+	Status     string
+}
+
+// Error describes the failed request.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("awtrix responded to %v with non-2xx http status: %v", e.Endpoint, e.Status)
+}
+
+// Is reports whether target is ErrNon2xxResponse.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrNon2xxResponse
+}
+
+// RetryPolicy defines how requests to an Awtrix device are retried. Transport errors are only retried for idempotent
+// endpoints, responses are only retried when their status is one of RetryableStatuses.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts made for a request, including the first.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts.
+	MaxBackoff time.Duration
+	// Multiplier scales the backoff after each retry.
+	Multiplier float64
+	// Jitter is the fraction of each backoff that is randomised, between 0 and 1.
+	Jitter float64
+	// RetryableStatuses are the http statuses that are retried.
+	RetryableStatuses []int
+}
+
+const (
+	defaultMaxAttempts    = 4
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 8 * time.Second
+	defaultMultiplier     = 2
+	defaultJitter         = 0.2
+)
+
+// DefaultRetryPolicy returns a policy that rides out an Awtrix device briefly dropping off the network.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    defaultMaxAttempts,
+		InitialBackoff: defaultInitialBackoff,
+		MaxBackoff:     defaultMaxBackoff,
+		Multiplier:     defaultMultiplier,
+		Jitter:         defaultJitter,
+		RetryableStatuses: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// NoRetries returns a policy that makes a single attempt for each request.
+func NoRetries() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// Backoff returns the delay to wait after the given failed attempt, counting from one.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	backoff := float64(p.InitialBackoff)
+	for range attempt - 1 {
+		backoff *= multiplier
+	}
+
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		jitter := min(p.Jitter, 1)
+		// spreads the backoff evenly across [backoff*(1-jitter), backoff*(1+jitter)]
+		backoff *= 1 - jitter + 2*jitter*rand.Float64() //nolint:gosec // jitter does not need a secure random source
+	}
+
+	return time.Duration(backoff)
+}
+
+// Retryable reports whether a failed attempt at a request to endpoint should be retried under this policy.
+func (p RetryPolicy) Retryable(endpoint Endpoint, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return slices.Contains(p.RetryableStatuses, statusErr.StatusCode)
+	}
+
+	// the device may have acted on a request whose response was lost, so only requests it can repeat are retried
+	return endpoint.Idempotent()
+}
+
+// Do calls attempt, a request to endpoint, until it succeeds, returns an error that is not retryable, the policy's
+// attempts are exhausted or ctx is done. The error from the final attempt is returned.
+func (p RetryPolicy) Do(ctx context.Context, endpoint Endpoint, attempt func(context.Context) error) error {
+	maxAttempts := max(p.MaxAttempts, 1)
+
+	var err error
+
+	for attemptNumber := 1; ; attemptNumber++ {
+		err = attempt(ctx)
+		if err == nil || attemptNumber >= maxAttempts || !p.Retryable(endpoint, err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, fmt.Errorf("abandoned retrying awtrix request: %w", ctx.Err()))
+		case <-time.After(p.Backoff(attemptNumber)):
+		}
+	}
+}
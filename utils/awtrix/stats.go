@@ -0,0 +1,28 @@
+package awtrix
+
+// Stats is the device information reported by an Awtrix device's stats endpoint.
+//
+//nolint:tagliatelle // the stats endpoint reports lower and snake case keys such as bat_raw
+type Stats struct {
+	// https://blueforcer.github.io/awtrix3/#/api?id=status
+	Battery     int    `json:"bat"`
+	BatteryRaw  int    `json:"bat_raw"`
+	Type        int    `json:"type"`
+	Lux         int    `json:"lux"`
+	LDRRaw      int    `json:"ldr_raw"`
+	RAM         int    `json:"ram"`
+	Brightness  int    `json:"bri"`
+	Temperature int    `json:"temp"`
+	Humidity    int    `json:"hum"`
+	Uptime      uint64 `json:"uptime"` // seconds since the device booted
+	WifiSignal  int    `json:"wifi_signal"`
+	Messages    int    `json:"messages"`
+	Version     string `json:"version"`
+	Indicator1  bool   `json:"indicator1"`
+	Indicator2  bool   `json:"indicator2"`
+	Indicator3  bool   `json:"indicator3"`
+	App         string `json:"app"`
+	UID         string `json:"uid"`
+	Matrix      bool   `json:"matrix"`
+	IPAddress   string `json:"ip_address"`
+}
@@ -1,9 +1,9 @@
 package utils
 
-import "errors"
+import "github.com/t-monaghan/altar/utils/awtrix"
 
 // ErrClosingResponseBody describes the error encountered when a request fails whilst closing the response body.
-var ErrClosingResponseBody = errors.New("failed to close response body")
+var ErrClosingResponseBody = awtrix.ErrClosingResponseBody
 
 // ResponseStatusIsNot2xx is a helper function to decide if a http response is OK.
 func ResponseStatusIsNot2xx(httpStatus int) bool {
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"net/http"
 	"time"
 
@@ -9,10 +10,49 @@ import (
 
 // Routine defines the requirements for an object to be managed by an altar broker.
 type Routine interface {
-	Fetch(client *http.Client) error
+	// Fetch retrieves the routine's data, ctx carries the broker's deadline for the fetch and is cancelled on shutdown.
+	Fetch(ctx context.Context, client *http.Client) error
 	GetData() any
 	ShouldPushToAwtrix() bool
 	GetName() string
 	GetPollRate() time.Duration
 	GetGlobalConfig() awtrix.Config
 }
+
+// ForcePusher is implemented by routines that can request their next push is sent to the Awtrix device even when its
+// payload is identical to the last one delivered. Only pushes to endpoints with retained state, such as custom apps,
+// are skipped when unchanged, so notifications are always sent.
+type ForcePusher interface {
+	ShouldForcePush() bool
+}
+
+// EndpointProvider is implemented by routines to declare the Awtrix endpoint their data is pushed to. A broker can
+// only push routines that implement it, allowing routine types to be defined outside of altar.
+type EndpointProvider interface {
+	Endpoint() (awtrix.Endpoint, error)
+}
+
+// Remover is implemented by routines that can remove their content from the Awtrix device. While ShouldRemove returns
+// true the broker pushes an empty payload to the routine's endpoint, which removes a custom app from the device's loop.
+type Remover interface {
+	ShouldRemove() bool
+}
+
+// Paginator is implemented by routines that can push a list of pages to a custom app. The device shows each page as its
+// own app, named with the page's index appended to the app's name, such as "name0" and "name1".
+type Paginator interface {
+	// PageCount returns the number of pages in the routine's data, and false when its data is a single app.
+	PageCount() (int, bool)
+}
+
+// Dismisser is implemented by routines that can dismiss the notification the Awtrix device is showing, such as a
+// notification held until it is dismissed. The broker dismisses the notification before pushing the routine's data.
+type Dismisser interface {
+	ShouldDismiss() bool
+}
+
+// Waker is implemented by routines that limit how often they fetch, such as by their poll rate. The broker calls Wake
+// before running a routine that has been woken ahead of its poll, so that its fetch is not skipped.
+type Waker interface {
+	Wake()
+}